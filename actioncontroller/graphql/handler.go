@@ -2,9 +2,11 @@ package graphql
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/activegraph/activegraph/activerecord"
 	"github.com/activegraph/activegraph/activesupport"
 
 	graphql "github.com/vektah/gqlparser/v2/ast"
@@ -41,12 +43,33 @@ func (rw *responseWriter) WriteData(k string, v interface{}) {
 	}
 }
 
+const (
+	// ErrCodeStaleObject is an error code returned when the record was changed
+	// by another request, clients could reload the record and retry.
+	ErrCodeStaleObject = "STALE_OBJECT"
+)
+
+// errorCode returns a machine-readable code of the error, or an empty string
+// when the error has no distinct code.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, new(activerecord.ErrStaleObject)):
+		return ErrCodeStaleObject
+	default:
+		return ""
+	}
+}
+
 func (rw *responseWriter) WriteError(err error) {
-	if err != nil {
-		rw.errors = append(rw.errors, activesupport.Hash{
-			"message": err.Error(),
-		})
+	if err == nil {
+		return
+	}
+
+	gqlerr := activesupport.Hash{"message": err.Error()}
+	if code := errorCode(err); code != "" {
+		gqlerr["extensions"] = activesupport.Hash{"code": code}
 	}
+	rw.errors = append(rw.errors, gqlerr)
 }
 
 func (rw *responseWriter) MarshalJSON() ([]byte, error) {
@@ -98,6 +98,19 @@ func introspectInputFields(fields graphql.FieldList, schema *graphql.Schema) []a
 	return fieldsIntrospection
 }
 
+func introspectEnumValues(values graphql.EnumValueList) []activesupport.Hash {
+	valuesIntrospection := make([]activesupport.Hash, 0, len(values))
+	for _, def := range values {
+		valuesIntrospection = append(valuesIntrospection, activesupport.Hash{
+			"name":              def.Name,
+			"description":       def.Description,
+			"isDeprecated":      false,
+			"deprecationReason": nil,
+		})
+	}
+	return valuesIntrospection
+}
+
 func introspect(schema *graphql.Schema) activesupport.Hash {
 	typesIntrospection := make([]activesupport.Hash, 0, len(schema.Types))
 	for _, def := range schema.Types {
@@ -112,9 +125,12 @@ func introspect(schema *graphql.Schema) activesupport.Hash {
 			"possibleTypes": nil,
 		}
 
-		if def.Kind == graphql.InputObject {
+		switch def.Kind {
+		case graphql.InputObject:
 			typeIntrospection["inputFields"] = introspectInputFields(def.Fields, schema)
-		} else {
+		case graphql.Enum:
+			typeIntrospection["enumValues"] = introspectEnumValues(def.EnumValues)
+		default:
 			typeIntrospection["fields"] = introspectFields(def.Fields, schema)
 		}
 
@@ -24,17 +24,33 @@ func (e ErrConstraintNotFound) Error() string {
 }
 
 func scalarconv(t activerecord.Type) *graphql.Type {
-	switch t := t.(type) {
+	if t, ok := t.(activerecord.Nil); ok {
+		return scalarconv(t.Type).Elem
+	}
+
+	// Scalars registered by the application take precedence over built-in scalars.
+	if def, ok := lookupScalar(t); ok {
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(def.Name, nil)}
+	}
+
+	switch t.(type) {
 	case *activerecord.Int64:
 		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(Int.Name, nil)}
-	case *activerecord.String:
-		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(String.Name, nil)}
+	case *activerecord.Float64:
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(Float.Name, nil)}
+	case *activerecord.Boolean:
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(Boolean.Name, nil)}
 	case *activerecord.DateTime:
 		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(DateTime.Name, nil)}
-	case activerecord.Nil:
-		return scalarconv(t.Type).Elem
+	case *activerecord.Decimal:
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(Decimal.Name, nil)}
+	case *activerecord.UUID:
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(UUID.Name, nil)}
+	case *activerecord.Binary:
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(Binary.Name, nil)}
 	default:
-		panic(t.String())
+		// Strings, enums and values of unknown types are represented as strings.
+		return &graphql.Type{NonNull: true, Elem: graphql.NamedType(String.Name, nil)}
 	}
 }
 
@@ -42,8 +58,63 @@ func CanonicalModelName(modelName string) string {
 	return strings.Title(modelName)
 }
 
+// CanonicalEnumName returns the name of the enum type of the model attribute,
+// e.g. "BookPublicationStatus" for "publication_status" attribute of "book" model.
+func CanonicalEnumName(modelName, attrName string) string {
+	name := CanonicalModelName(modelName)
+	for _, part := range strings.Split(attrName, "_") {
+		name += strings.Title(part)
+	}
+	return name
+}
+
+// typeconv converts the attribute type of the model into the GraphQL type.
+// Enum attributes are represented by dedicated enum types, which are registered
+// within the schema, values of enums are used as names of enum values as is.
+// Enums with values, which are not valid names, are represented as strings.
+func (s *Schema) typeconv(model *activerecord.Relation, attr activerecord.Attribute) *graphql.Type {
+	t, nonNull := attr.AttributeType(), true
+	if nilType, ok := t.(activerecord.Nil); ok {
+		t, nonNull = nilType.Type, false
+	}
+
+	enum, ok := t.(*activerecord.Enum)
+	if !ok || len(enum.Values) == 0 {
+		return scalarconv(attr.AttributeType())
+	}
+	for _, value := range enum.Values {
+		if !activerecord.IsEnumValueName(value) {
+			return scalarconv(attr.AttributeType())
+		}
+	}
+
+	name := CanonicalEnumName(model.Name(), attr.AttributeName())
+	if _, ok := s.root.Types[name]; !ok {
+		values := make(graphql.EnumValueList, 0, len(enum.Values))
+		for _, value := range enum.Values {
+			values = append(values, &graphql.EnumValueDefinition{Name: value})
+		}
+
+		s.root.Types[name] = &graphql.Definition{
+			Kind:       graphql.Enum,
+			Name:       name,
+			Interfaces: make([]string, 0),
+			EnumValues: values,
+		}
+	}
+
+	if !nonNull {
+		return graphql.NamedType(name, nil)
+	}
+	return &graphql.Type{NonNull: true, Elem: graphql.NamedType(name, nil)}
+}
+
 type Schema struct {
 	root *graphql.Schema
+
+	// params are attributes permitted by actions of mapped resources, by
+	// names of models and actions.
+	params map[string]map[string][]activerecord.Attribute
 }
 
 func (s *Schema) AddIndexOp(model *activerecord.Relation) *graphql.FieldDefinition {
@@ -87,15 +158,22 @@ func (s *Schema) AddCreateOp(
 
 	inputName := "Create" + CanonicalModelName(model.Name()) + "Input"
 	inputFields := make(graphql.FieldList, 0, len(inputs))
+	counters := counterCacheColumns(model)
 
 	for _, input := range inputs {
-		// TODO: add support of objects.
+		// Counter caches are maintained by the model.
+		if _, ok := counters[input.AttributeName()]; ok {
+			continue
+		}
 		inputFields = append(inputFields, &graphql.FieldDefinition{
 			Name: input.AttributeName(),
-			Type: scalarconv(input.AttributeType()),
+			Type: s.typeconv(model, input),
 		})
 	}
 
+	// Records of associations are created along with the record.
+	inputFields = append(inputFields, s.nestedInputFields(model, actioncontroller.ActionCreate)...)
+
 	s.root.Types[inputName] = &graphql.Definition{
 		Kind:       graphql.InputObject,
 		Name:       inputName,
@@ -118,6 +196,183 @@ func (s *Schema) AddCreateOp(
 	return def
 }
 
+// nestedInputFields returns input fields of associations accepting nested
+// attributes of the model, e.g. "books: [CreateAuthorBooksAttributesInput!]".
+// All fields of nested inputs are optional.
+//
+// Nested inputs of the create operation contain attributes of new records.
+// Nested inputs of the update operation also contain identifiers of records
+// to update or delete. When the associated model is mapped as a resource,
+// only attributes permitted by the same action of the resource are accepted.
+func (s *Schema) nestedInputFields(
+	model *activerecord.Relation, actionName string,
+) graphql.FieldList {
+	var fields graphql.FieldList
+
+	for _, nested := range model.ReflectOnAllNestedAttributes() {
+		assoc := model.ReflectOnAssociation(nested.AssociationName)
+		if assoc == nil {
+			continue
+		}
+
+		inputName := CanonicalModelName(actionName) + CanonicalModelName(model.Name()) +
+			CanonicalModelName(nested.AssociationName) + "AttributesInput"
+
+		if _, ok := s.root.Types[inputName]; !ok {
+			targets := assoc.Relation
+
+			var inputFields graphql.FieldList
+			if actionName == actioncontroller.ActionUpdate {
+				primaryKey := targets.AttributeForInspect(targets.PrimaryKey())
+				inputFields = append(inputFields, &graphql.FieldDefinition{
+					Name: primaryKey.AttributeName(),
+					Type: nullable(s.typeconv(targets, primaryKey)),
+				})
+			}
+
+			for _, attr := range s.nestedAttributes(targets, assoc.AssociationForeignKey(), actionName) {
+				inputFields = append(inputFields, &graphql.FieldDefinition{
+					Name: attr.AttributeName(),
+					Type: nullable(s.typeconv(targets, attr)),
+				})
+			}
+			if actionName == actioncontroller.ActionUpdate && nested.AllowDestroy {
+				inputFields = append(inputFields, &graphql.FieldDefinition{
+					Name: activerecord.DestroyAttributeName,
+					Type: graphql.NamedType(Boolean.Name, nil),
+				})
+			}
+			// Input objects must define at least one field.
+			if len(inputFields) == 0 {
+				continue
+			}
+
+			s.root.Types[inputName] = &graphql.Definition{
+				Kind:       graphql.InputObject,
+				Name:       inputName,
+				Fields:     inputFields,
+				Interfaces: make([]string, 0),
+			}
+		}
+
+		fields = append(fields, &graphql.FieldDefinition{
+			Name: nested.AssociationName,
+			Type: &graphql.Type{
+				Elem: &graphql.Type{NonNull: true, Elem: graphql.NamedType(inputName, nil)},
+			},
+		})
+	}
+	return fields
+}
+
+// nestedAttributes returns attributes of associated records, which could be
+// assigned through nested attributes of the owner. The primary key, the
+// reference to the owner and columns maintained by the model are omitted.
+func (s *Schema) nestedAttributes(
+	targets *activerecord.Relation, foreignKey, actionName string,
+) []activerecord.Attribute {
+	omitted := counterCacheColumns(targets)
+	for _, name := range []string{
+		targets.PrimaryKey(), foreignKey, targets.LockingColumn(), targets.SoftDeleteColumn(),
+	} {
+		omitted[name] = ""
+	}
+
+	attrs := targets.AttributesForInspect()
+	if permits, ok := s.params[targets.Name()]; ok {
+		attrs = permits[actionName]
+	}
+
+	var nested []activerecord.Attribute
+	for _, attr := range attrs {
+		if _, ok := omitted[attr.AttributeName()]; ok {
+			continue
+		}
+		if v, ok := attr.(activerecord.VirtualAttribute); ok && v.IsComputed() {
+			continue
+		}
+		nested = append(nested, attr)
+	}
+	return nested
+}
+
+// counterCacheColumns returns columns of the model caching the number of
+// targets of collections, mapped to names of collections.
+func counterCacheColumns(model *activerecord.Relation) map[string]string {
+	columns := make(map[string]string)
+	for _, assoc := range model.ReflectOnAllAssociations() {
+		if hasMany, ok := assoc.Association.(*activerecord.HasMany); ok {
+			if column := hasMany.CounterCacheColumn(); column != "" {
+				columns[column] = assoc.Name
+			}
+		}
+	}
+	return columns
+}
+
+// nullable returns a nullable version of the type.
+func nullable(t *graphql.Type) *graphql.Type {
+	if t.NonNull {
+		return t.Elem
+	}
+	return t
+}
+
+func (s *Schema) AddUpdateOp(
+	model *activerecord.Relation, action actioncontroller.Action,
+) *graphql.FieldDefinition {
+	var inputs []activerecord.Attribute
+	if constraints := action.ActionConstraints(); constraints.Request != nil {
+		inputs = constraints.Request.Attributes
+	}
+
+	inputName := "Update" + CanonicalModelName(model.Name()) + "Input"
+	inputFields := make(graphql.FieldList, 0, len(inputs))
+
+	counters := counterCacheColumns(model)
+
+	// All fields of the update input are optional, so the record could be
+	// updated partially.
+	for _, input := range inputs {
+		if _, ok := counters[input.AttributeName()]; ok {
+			continue
+		}
+		inputFields = append(inputFields, &graphql.FieldDefinition{
+			Name: input.AttributeName(),
+			Type: nullable(s.typeconv(model, input)),
+		})
+	}
+
+	// Records of associations are created, updated and deleted along with
+	// the record.
+	inputFields = append(inputFields, s.nestedInputFields(model, actioncontroller.ActionUpdate)...)
+
+	s.root.Types[inputName] = &graphql.Definition{
+		Kind:       graphql.InputObject,
+		Name:       inputName,
+		Fields:     inputFields,
+		Interfaces: make([]string, 0),
+	}
+
+	def := &graphql.FieldDefinition{
+		Name: "update" + CanonicalModelName(model.Name()),
+		Arguments: graphql.ArgumentDefinitionList{
+			{
+				Name: model.PrimaryKey(),
+				Type: scalarconv(model.AttributeForInspect(model.PrimaryKey()).AttributeType()),
+			},
+			{
+				Name: model.Name(),
+				Type: &graphql.Type{NonNull: true, Elem: graphql.NamedType(inputName, nil)},
+			},
+		},
+		Type: graphql.NamedType(CanonicalModelName(model.Name()), nil),
+	}
+
+	s.root.Mutation.Fields = append(s.root.Mutation.Fields, def)
+	return def
+}
+
 func (s *Schema) AddDestroyOp(model *activerecord.Relation) *graphql.FieldDefinition {
 	def := &graphql.FieldDefinition{
 		Name: "delete" + CanonicalModelName(model.Name()),
@@ -150,14 +405,25 @@ func (s *Schema) AddModel(model *activerecord.Relation) *graphql.Definition {
 
 		attrs := model.AttributesForInspect()
 		assocs := model.ReflectOnAllAssociations()
+		counters := counterCacheColumns(model)
 
 		fields := make(graphql.FieldList, 0, len(attrs)+len(assocs))
 
 		for _, attr := range attrs {
-			fields = append(fields, &graphql.FieldDefinition{
+			// Values of virtual attributes are never read from the database,
+			// so only computed ones are exposed.
+			if v, ok := attr.(activerecord.VirtualAttribute); ok && !v.IsComputed() {
+				continue
+			}
+
+			field := &graphql.FieldDefinition{
 				Name: attr.AttributeName(),
-				Type: scalarconv(attr.AttributeType()),
-			})
+				Type: s.typeconv(model, attr),
+			}
+			if collName, ok := counters[attr.AttributeName()]; ok {
+				field.Description = fmt.Sprintf("The cached number of %s.", collName)
+			}
+			fields = append(fields, field)
 		}
 
 		for _, assoc := range assocs {
@@ -268,10 +534,31 @@ func (m *Mapper) Map() (http.Handler, error) {
 	schema.Types["Boolean"] = Boolean
 	schema.Types["String"] = String
 	schema.Types["DateTime"] = DateTime
+	schema.Types["Decimal"] = Decimal
+	schema.Types["UUID"] = UUID
+	schema.Types["Binary"] = Binary
 
-	rootSchema := Schema{schema}
+	for _, def := range scalars() {
+		schema.Types[def.Name] = def
+	}
+
+	rootSchema := Schema{root: schema, params: make(map[string]map[string][]activerecord.Attribute)}
 	routing := NewRoutingTable()
 
+	// Collect permitted attributes before adding operations, since inputs of
+	// nested attributes depend on permitted attributes of associated models.
+	for _, resource := range m.resources {
+		model := resource.model.(*activerecord.Relation)
+		permits := make(map[string][]activerecord.Attribute)
+
+		for _, action := range resource.controller.ActionMethods() {
+			if constraints := action.ActionConstraints(); constraints.Request != nil {
+				permits[action.ActionName()] = constraints.Request.Attributes
+			}
+		}
+		rootSchema.params[model.Name()] = permits
+	}
+
 	for _, resource := range m.resources {
 		model := resource.model.(*activerecord.Relation)
 		rootSchema.AddModel(model)
@@ -287,6 +574,9 @@ func (m *Mapper) Map() (http.Handler, error) {
 			case actioncontroller.ActionCreate:
 				op := rootSchema.AddCreateOp(model, action)
 				routing.AddOperation(op.Name, action)
+			case actioncontroller.ActionUpdate:
+				op := rootSchema.AddUpdateOp(model, action)
+				routing.AddOperation(op.Name, action)
 			case actioncontroller.ActionDestroy:
 				op := rootSchema.AddDestroyOp(model)
 				routing.AddOperation(op.Name, action)
@@ -306,6 +596,9 @@ func (m *Mapper) Map() (http.Handler, error) {
 			for _, selection := range op.SelectionSet {
 				field := selection.(*graphql.Field)
 				data, err := routing.Dispatch(r, field)
+				if err == nil {
+					data, err = marshalScalars(field.SelectionSet, data)
+				}
 
 				rw.WriteError(err)
 				rw.WriteData(field.Name, data)
@@ -0,0 +1,474 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/activegraph/activegraph/actioncontroller"
+	"github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/actionview"
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+// serve executes the GraphQL query and returns the status and the body of
+// the response.
+func serve(t *testing.T, h http.Handler, query string) (int, string) {
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
+	r.Header.Set("Content-Type", "application/graphql")
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	return rw.Code, rw.Body.String()
+}
+
+func TestMapper_NestedAttributes(t *testing.T) {
+	reg := activerecord.NewRegistry()
+
+	_, err := reg.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+		})
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.String("isbn")
+			t.Int64("lock_version")
+			t.DateTime("deleted_at")
+			t.References("authors")
+		})
+	})
+
+	Book := reg.New("book", func(r *activerecord.R) {
+		r.BelongsTo("author")
+		r.SoftDelete("deleted_at")
+	})
+	Author := reg.New("author", func(r *activerecord.R) {
+		r.HasMany("books")
+		r.AcceptsNestedAttributesFor("books", activerecord.AllowDestroy())
+	})
+
+	AuthorController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Author.AttributesForInspect("name"), "create", "update")
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.NestedView(ctx, Author.Create(ctx.Params.Get("author")))
+		})
+		c.Update(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			author := Author.Find(ctx.Params["id"])
+			author = author.AssignAttributes(ctx.Params.Get("author")).Update()
+			return actionview.NestedView(ctx, author)
+		})
+	})
+
+	// The ISBN of the book could be assigned only on creation.
+	BookController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Book.AttributesForInspect("title", "isbn"), "create")
+		c.Permit(Book.AttributesForInspect("title"), "update")
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.NestedView(ctx, Book.Create(ctx.Params.Get("book")))
+		})
+		c.Update(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			book := Book.Find(ctx.Params["id"])
+			book = book.AssignAttributes(ctx.Params.Get("book")).Update()
+			return actionview.NestedView(ctx, book)
+		})
+	})
+
+	var mapper graphql.Mapper
+	mapper.Resources(Author, AuthorController)
+	mapper.Resources(Book, BookController)
+
+	h, err := mapper.Map()
+	require.NoError(t, err)
+
+	code, body := serve(t, h, `mutation {
+		createAuthor(author: {name: "Frank Herbert", books: [{title: "Dune", isbn: "0-441-17271-7"}]}) {
+			name
+		}
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"data": {"createAuthor": {"name": "Frank Herbert"}}}`, body)
+
+	author := Author.First().Unwrap()
+	book := Book.First().Unwrap()
+	require.Equal(t, author.ID(), book.Attribute("author_id"))
+
+	// Only attributes permitted by the resource of the associated model are
+	// accepted, records are updated and deleted only by the update operation.
+	for _, books := range []string{
+		`{title: "Dune", lock_version: 42}`,
+		`{title: "Dune", deleted_at: "2021-01-01T00:00:00Z"}`,
+		`{title: "Dune", author_id: 42}`,
+		`{id: 1, title: "Dune"}`,
+		`{id: 1, _destroy: true}`,
+	} {
+		code, body = serve(t, h, `mutation {
+			createAuthor(author: {name: "Brian Herbert", books: [`+books+`]}) { name }
+		}`)
+		require.Equal(t, http.StatusOK, code, body)
+		require.Contains(t, body, "is not defined by type CreateAuthorBooksAttributesInput")
+	}
+
+	code, body = serve(t, h, `mutation {
+		updateAuthor(id: 1, author: {books: [{id: 1, isbn: "0-441-17271-7"}]}) { name }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.Contains(t, body, `field \"isbn\" is not defined by type UpdateAuthorBooksAttributesInput`)
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+
+	code, body = serve(t, h, `mutation {
+		updateAuthor(id: 1, author: {books: [{id: 1, title: "Dune Messiah"}, {title: "Children of Dune"}]}) {
+			name
+		}
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.Equal(t, "Dune Messiah", Book.Find(book.ID()).Unwrap().Attribute("title"))
+
+	code, body = serve(t, h, `mutation {
+		updateAuthor(id: 1, author: {books: [{id: 1, _destroy: true}]}) { name }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+
+	books, err := Book.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Children of Dune", books[0].Attribute("title"))
+}
+
+// Email is a value object of the email address.
+type Email struct {
+	User   string
+	Domain string
+}
+
+func (e Email) String() string {
+	return e.User + "@" + e.Domain
+}
+
+// EmailAddress is a custom attribute type of email addresses.
+type EmailAddress struct{}
+
+func (*EmailAddress) NativeType() string { return "EMAIL" }
+
+func (*EmailAddress) String() string { return "email" }
+
+func (ea *EmailAddress) Deserialize(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case Email:
+		return value, nil
+	case string:
+		parts := strings.Split(value, "@")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, activerecord.ErrType{TypeName: ea.String(), Value: value}
+		}
+		return Email{User: parts[0], Domain: parts[1]}, nil
+	default:
+		return nil, activerecord.ErrType{TypeName: ea.String(), Value: value}
+	}
+}
+
+func (ea *EmailAddress) Serialize(value interface{}) (interface{}, error) {
+	email, err := ea.Deserialize(value)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprint(email), nil
+}
+
+func TestMapper_RegisterScalar(t *testing.T) {
+	activerecord.RegisterType(new(EmailAddress))
+
+	graphql.RegisterScalar(new(EmailAddress),
+		&ast.Definition{Kind: ast.Scalar, Name: "Email"},
+		graphql.UnmarshalerFunc(func(raw string) (interface{}, error) {
+			return new(EmailAddress).Deserialize(raw)
+		}),
+		graphql.MarshalerFunc(func(value interface{}) (string, error) {
+			email, ok := value.(Email)
+			if !ok {
+				return "", fmt.Errorf("%v is not an email", value)
+			}
+			return email.String(), nil
+		}),
+	)
+
+	reg := activerecord.NewRegistry()
+
+	_, err := reg.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.DefineColumn("email", new(EmailAddress), activerecord.NotNull())
+		})
+	})
+
+	User := reg.New("user")
+
+	UserController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(User.AttributesForInspect("email"), "create")
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.NestedView(ctx, User.Create(ctx.Params.Get("user")))
+		})
+		c.Show(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.NestedView(ctx, User.Find(ctx.Params["id"]))
+		})
+	})
+
+	var mapper graphql.Mapper
+	mapper.Resources(User, UserController)
+
+	h, err := mapper.Map()
+	require.NoError(t, err)
+
+	code, body := serve(t, h, `mutation {
+		createUser(user: {email: "lem@example.com"}) { id email }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"data": {"createUser": {"id": 1, "email": "lem@example.com"}}}`, body)
+
+	code, body = serve(t, h, `{ user(id: 1) { email } }`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"data": {"user": {"email": "lem@example.com"}}}`, body)
+
+	require.Equal(t, Email{User: "lem", Domain: "example.com"}, User.Find(1).Unwrap().Attribute("email"))
+}
+
+func TestMapper_Enum(t *testing.T) {
+	reg := activerecord.NewRegistry()
+
+	_, err := reg.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.String("status")
+		})
+	})
+
+	Book := reg.New("book", func(r *activerecord.R) {
+		r.Enum("status", "draft", "in_progress", "published")
+	})
+
+	BookController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Book.AttributesForInspect("title", "status"), "create")
+		c.Create(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			return actionview.NestedView(ctx, Book.Create(ctx.Params.Get("book")))
+		})
+	})
+
+	var mapper graphql.Mapper
+	mapper.Resources(Book, BookController)
+
+	h, err := mapper.Map()
+	require.NoError(t, err)
+
+	code, body := serve(t, h, `query IntrospectionQuery {
+		__schema { types { kind name enumValues { name } } }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+
+	var resp struct {
+		Data struct {
+			Schema struct {
+				Types []struct {
+					Kind       string
+					Name       string
+					EnumValues []struct{ Name string }
+				}
+			} `json:"__schema"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+	var values []string
+	for _, typ := range resp.Data.Schema.Types {
+		if typ.Name != "BookStatus" {
+			continue
+		}
+		require.Equal(t, "ENUM", typ.Kind)
+		for _, value := range typ.EnumValues {
+			values = append(values, value.Name)
+		}
+	}
+	require.Equal(t, []string{"draft", "in_progress", "published"}, values)
+
+	code, body = serve(t, h, `mutation {
+		createBook(book: {title: "Dune", status: in_progress}) { title status }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"data": {"createBook": {"title": "Dune", "status": "in_progress"}}}`, body)
+}
+
+func TestMapper_StaleObject(t *testing.T) {
+	reg := activerecord.NewRegistry()
+
+	_, err := reg.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.LockVersion()
+		})
+	})
+
+	Book := reg.New("book")
+	Book.Create(activesupport.Hash{"title": "Dune"}).Unwrap()
+
+	BookController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Book.AttributesForInspect("title", "lock_version"), "update")
+		c.Update(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			book := Book.Find(ctx.Params["id"])
+			book = book.AssignAttributes(ctx.Params.Get("book")).Update()
+			return actionview.NestedView(ctx, book)
+		})
+	})
+
+	var mapper graphql.Mapper
+	mapper.Resources(Book, BookController)
+
+	h, err := mapper.Map()
+	require.NoError(t, err)
+
+	// Both requests read the same version of the book, so the second one
+	// must fail with a distinct code.
+	const update = `mutation {
+		updateBook(id: 1, book: {title: "%s", lock_version: 0}) { title lock_version }
+	}`
+
+	code, body := serve(t, h, fmt.Sprintf(update, "Dune Messiah"))
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"data": {"updateBook": {"title": "Dune Messiah", "lock_version": 1}}}`, body)
+
+	code, body = serve(t, h, fmt.Sprintf(update, "Children of Dune"))
+	require.Equal(t, http.StatusOK, code, body)
+
+	var resp struct {
+		Errors []struct {
+			Message    string
+			Extensions struct{ Code string }
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	require.Len(t, resp.Errors, 1, body)
+	require.Equal(t, "STALE_OBJECT", resp.Errors[0].Extensions.Code)
+
+	require.Equal(t, "Dune Messiah", Book.First().Unwrap().Attribute("title"))
+}
+
+func TestMapper_CounterCache(t *testing.T) {
+	reg := activerecord.NewRegistry()
+
+	_, err := reg.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+			t.Int64("books_count", activerecord.NotNull(), activerecord.Default(int64(0)))
+		})
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.References("authors")
+		})
+	})
+
+	reg.New("book", func(r *activerecord.R) {
+		r.BelongsTo("author", activerecord.CounterCache())
+	})
+	Author := reg.New("author", func(r *activerecord.R) {
+		r.HasMany("books")
+	})
+
+	AuthorController := actioncontroller.New(func(c *actioncontroller.C) {
+		c.Permit(Author.AttributesForInspect("name", "books_count"), "update")
+		c.Update(func(ctx *actioncontroller.Context) actioncontroller.Result {
+			author := Author.Find(ctx.Params["id"])
+			author = author.AssignAttributes(ctx.Params.Get("author")).Update()
+			return actionview.NestedView(ctx, author)
+		})
+	})
+
+	var mapper graphql.Mapper
+	mapper.Resources(Author, AuthorController)
+
+	h, err := mapper.Map()
+	require.NoError(t, err)
+
+	code, body := serve(t, h, `query IntrospectionQuery {
+		__schema { types { name fields { name description } inputFields { name } } }
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+
+	var resp struct {
+		Data struct {
+			Schema struct {
+				Types []struct {
+					Name        string
+					Fields      []struct{ Name, Description string }
+					InputFields []struct{ Name string }
+				}
+			} `json:"__schema"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+	var description string
+	for _, typ := range resp.Data.Schema.Types {
+		switch typ.Name {
+		case "Author":
+			for _, field := range typ.Fields {
+				if field.Name == "books_count" {
+					description = field.Description
+				}
+			}
+		case "UpdateAuthorInput":
+			// Counters are maintained by the database only.
+			for _, field := range typ.InputFields {
+				require.NotEqual(t, "books_count", field.Name)
+			}
+		}
+	}
+	require.Equal(t, "The cached number of books.", description)
+}
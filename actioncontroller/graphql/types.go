@@ -1,12 +1,16 @@
 package graphql
 
 import (
+	"encoding/base64"
 	"fmt"
+	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	graphql "github.com/vektah/gqlparser/v2/ast"
 
+	"github.com/activegraph/activegraph/activerecord"
 	"github.com/activegraph/activegraph/activesupport"
 )
 
@@ -19,6 +23,116 @@ func init() {
 	DefaultDecoder.RegisterName(Boolean.Name, UnmarshalerFunc(UnmarshalBoolean))
 	// Non-standard types.
 	DefaultDecoder.RegisterName(DateTime.Name, UnmarshalerFunc(UnmarshalDateTime))
+	DefaultDecoder.RegisterName(Decimal.Name, UnmarshalerFunc(UnmarshalDecimal))
+	DefaultDecoder.RegisterName(UUID.Name, UnmarshalerFunc(UnmarshalUUID))
+	DefaultDecoder.RegisterName(Binary.Name, UnmarshalerFunc(UnmarshalBinary))
+}
+
+var registeredScalars = struct {
+	sync.RWMutex
+	types      map[reflect.Type]*graphql.Definition
+	marshalers map[string]Marshaler
+}{
+	types:      make(map[reflect.Type]*graphql.Definition),
+	marshalers: make(map[string]Marshaler),
+}
+
+// RegisterScalar registers the scalar definition used to represent values of
+// the attribute type, the unmarshaler is registered within DefaultDecoder to
+// decode arguments of the scalar type, the marshaler encodes values of the
+// scalar type in responses.
+//
+//	var Email = &graphql.Definition{Kind: graphql.Scalar, Name: "Email"}
+//
+//	activerecord.RegisterType(new(EmailAddress), "email")
+//	graphql.RegisterScalar(new(EmailAddress), Email,
+//		UnmarshalerFunc(ParseEmail), MarshalerFunc(FormatEmail))
+//
+// RegisterScalar panics, when the type is already registered.
+func RegisterScalar(
+	t activerecord.Type, def *graphql.Definition, unmarshaler Unmarshaler, marshaler Marshaler,
+) {
+	registeredScalars.Lock()
+	defer registeredScalars.Unlock()
+
+	typ := reflect.TypeOf(t)
+	if _, dup := registeredScalars.types[typ]; dup {
+		panic(fmt.Sprintf("graphql: registering duplicate scalar of %s type", t))
+	}
+	if marshaler == nil {
+		panic(fmt.Sprintf("graphql: registering scalar of %s type without marshaler", t))
+	}
+
+	DefaultDecoder.RegisterName(def.Name, unmarshaler)
+	registeredScalars.types[typ] = def
+	registeredScalars.marshalers[def.Name] = marshaler
+}
+
+// lookupMarshaler returns the marshaler of the registered scalar.
+func lookupMarshaler(name string) (Marshaler, bool) {
+	registeredScalars.RLock()
+	defer registeredScalars.RUnlock()
+
+	marshaler, ok := registeredScalars.marshalers[name]
+	return marshaler, ok
+}
+
+// marshalScalars replaces values of registered scalars selected by the
+// selection set with their marshaled representation.
+func marshalScalars(set graphql.SelectionSet, data interface{}) (interface{}, error) {
+	switch data := data.(type) {
+	case []activesupport.Hash:
+		for i := range data {
+			if _, err := marshalScalars(set, data[i]); err != nil {
+				return nil, err
+			}
+		}
+	case activesupport.Hash:
+		for _, selection := range set {
+			field, ok := selection.(*graphql.Field)
+			if !ok || field.Definition == nil || data[field.Name] == nil {
+				continue
+			}
+			if len(field.SelectionSet) != 0 {
+				if _, err := marshalScalars(field.SelectionSet, data[field.Name]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			marshaler, ok := lookupMarshaler(field.Definition.Type.Name())
+			if !ok {
+				continue
+			}
+			value, err := marshaler.Marshal(data[field.Name])
+			if err != nil {
+				return nil, err
+			}
+			data[field.Name] = value
+		}
+	}
+	return data, nil
+}
+
+// lookupScalar returns the scalar definition registered for the attribute type.
+func lookupScalar(t activerecord.Type) (*graphql.Definition, bool) {
+	registeredScalars.RLock()
+	defer registeredScalars.RUnlock()
+
+	def, ok := registeredScalars.types[reflect.TypeOf(t)]
+	return def, ok
+}
+
+// scalars returns all registered scalar definitions.
+func scalars() []*graphql.Definition {
+	registeredScalars.RLock()
+	defer registeredScalars.RUnlock()
+
+	defs := make([]*graphql.Definition, 0, len(registeredScalars.types))
+	for _, def := range registeredScalars.types {
+		defs = append(defs, def)
+	}
+	return defs
 }
 
 // ErrUnsupportedType is returned by Decoder when attempting to decode
@@ -31,10 +145,32 @@ func (e ErrUnsupportedType) Error() string {
 	return "graphql: unsupported type " + e.Type.String()
 }
 
+// ErrUnknownField is returned by Decoder when the input object contains a field,
+// which is not defined by the type of the input object.
+type ErrUnknownField struct {
+	FieldName string
+	TypeName  string
+}
+
+func (e ErrUnknownField) Error() string {
+	return fmt.Sprintf("graphql: field %q is not defined by type %s", e.FieldName, e.TypeName)
+}
+
 type Unmarshaler interface {
 	Unmarshal(raw string) (interface{}, error)
 }
 
+type Marshaler interface {
+	Marshal(value interface{}) (string, error)
+}
+
+// MarshalerFunc is a function adapter for Marshaler interface.
+type MarshalerFunc func(value interface{}) (string, error)
+
+func (fn MarshalerFunc) Marshal(value interface{}) (string, error) {
+	return fn(value)
+}
+
 // UnmarshalerFunc is a function adapter for Unmarshaler interface.
 type UnmarshalerFunc func(raw string) (interface{}, error)
 
@@ -85,6 +221,11 @@ func (d *Decoder) Decode(v *graphql.Value) (interface{}, error) {
 	case graphql.ObjectValue:
 		object := activesupport.Hash{}
 		for _, child := range v.Children {
+			// Values of fields, which are not defined by the input type,
+			// are left without the expected type.
+			if child.Value != nil && child.Value.ExpectedType == nil {
+				return object, ErrUnknownField{FieldName: child.Name, TypeName: v.ExpectedType.Name()}
+			}
 			element, err := d.Decode(child.Value)
 			if err != nil {
 				return object, err
@@ -92,6 +233,10 @@ func (d *Decoder) Decode(v *graphql.Value) (interface{}, error) {
 			object[child.Name] = element
 		}
 		return object, nil
+	case graphql.EnumValue:
+		// Enum values are validated against the schema, so the name of the
+		// value is returned as is.
+		return v.Raw, nil
 	}
 
 	decoder, ok := d.types[v.ExpectedType.Name()]
@@ -160,3 +305,37 @@ var DateTime = &graphql.Definition{
 func UnmarshalDateTime(raw string) (interface{}, error) {
 	return time.Parse(iso8601, raw)
 }
+
+// Decimal is a scalar type that represents an arbitrary-precision decimal
+// number encoded as a string.
+var Decimal = &graphql.Definition{
+	Kind:        graphql.Scalar,
+	Name:        "Decimal",
+	Description: "An arbitrary-precision decimal number encoded as a string.",
+}
+
+func UnmarshalDecimal(raw string) (interface{}, error) {
+	return activesupport.ParseBigDecimal(raw)
+}
+
+// UUID is a scalar type that represents a universally unique identifier.
+var UUID = &graphql.Definition{
+	Kind:        graphql.Scalar,
+	Name:        "UUID",
+	Description: "A universally unique identifier.",
+}
+
+func UnmarshalUUID(raw string) (interface{}, error) {
+	return new(activerecord.UUID).Deserialize(raw)
+}
+
+// Binary is a scalar type that represents binary data encoded with base64.
+var Binary = &graphql.Definition{
+	Kind:        graphql.Scalar,
+	Name:        "Binary",
+	Description: "Binary data encoded with base64.",
+}
+
+func UnmarshalBinary(raw string) (interface{}, error) {
+	return base64.StdEncoding.DecodeString(raw)
+}
@@ -16,108 +16,307 @@ type ConnectionStatements interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
 
+// dryRunResult is a result of the statement written to the dry-run writer
+// instead of being executed.
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, nil }
+func (dryRunResult) RowsAffected() (int64, error) { return 1, nil }
+
+// Exec executes the statement. When the context has a dry-run writer, the
+// statement is written to the writer instead.
+func Exec(
+	ctx context.Context, conn ConnectionStatements, stmt string, args ...interface{},
+) (sql.Result, error) {
+	if w := activerecord.DryRunWriter(ctx); w != nil {
+		if len(args) != 0 {
+			_, err := fmt.Fprintf(w, "%s; -- %v\n", stmt, args)
+			return dryRunResult{}, err
+		}
+		_, err := fmt.Fprintf(w, "%s;\n", stmt)
+		return dryRunResult{}, err
+	}
+	return conn.ExecContext(ctx, stmt, args...)
+}
+
+// ConfigurePool applies connection pool settings of the configuration to
+// the database handle.
+func ConfigurePool(db *sql.DB, conf activerecord.DatabaseConfig) {
+	if conf.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(conf.MaxOpenConns)
+	}
+	if conf.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(conf.MaxIdleConns)
+	}
+	if conf.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+	}
+	if conf.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	}
+}
+
 type DatabaseStatements struct {
 	Conn ConnectionStatements
 }
 
-func (s *DatabaseStatements) buildInsertStmt(op *activerecord.InsertOperation) (string, error) {
+// valueSQL returns the placeholder of the serialized value, values are never
+// embedded into the statement and passed as arguments instead.
+func valueSQL(val interface{}, args []interface{}) (string, []interface{}) {
+	if val == nil {
+		return "NULL", args
+	}
+	return "?", append(args, val)
+}
+
+func (s *DatabaseStatements) buildInsertStmt(op *activerecord.InsertOperation) (
+	string, []interface{}, error,
+) {
+	rows := op.Rows
+	if len(rows) == 0 {
+		rows = [][]activerecord.ColumnValue{op.ColumnValues}
+	}
+
 	var (
 		colBuf strings.Builder
 		valBuf strings.Builder
+		args   []interface{}
 	)
 
-	colPos, colNum := 0, len(op.ColumnValues)
-	for _, col := range op.ColumnValues {
-		val, err := col.Type.Serialize(col.Value)
-		if err != nil {
-			return "", err
+	columns := make([]string, 0, len(rows[0]))
+	for _, col := range rows[0] {
+		columns = append(columns, fmt.Sprintf("%q", col.Name))
+	}
+	colBuf.WriteString(strings.Join(columns, ", "))
+
+	for rowPos, row := range rows {
+		if len(row) != len(columns) {
+			return "", nil, fmt.Errorf("expected %d columns in row %d, got %d", len(columns), rowPos, len(row))
+		}
+
+		values := make([]string, 0, len(row))
+		for _, col := range row {
+			val, err := col.Type.Serialize(col.Value)
+			if err != nil {
+				return "", nil, err
+			}
+
+			var valsql string
+			valsql, args = valueSQL(val, args)
+			values = append(values, valsql)
 		}
 
-		colfmt, valfmt := `"%s", `, `'%v', `
-		if colPos == colNum-1 {
-			colfmt, valfmt = `"%s"`, `'%v'`
+		if rowPos > 0 {
+			valBuf.WriteString(", ")
 		}
+		fmt.Fprintf(&valBuf, "(%s)", strings.Join(values, ", "))
+	}
+
+	const stmt = `INSERT INTO "%s" (%s) VALUES %s%s`
+	sql := fmt.Sprintf(stmt, op.TableName, colBuf.String(), valBuf.String(), onConflictSQL(op, rows[0]))
+	if op.Returning != "" {
+		sql += fmt.Sprintf(` RETURNING %q`, op.Returning)
+	}
+	return sql, args, nil
+}
+
+// onConflictSQL returns the conflict clause of the insert statement, on
+// update all inserted columns except conflicting ones are updated.
+func onConflictSQL(op *activerecord.InsertOperation, row []activerecord.ColumnValue) string {
+	if op.OnDuplicate == "" {
+		return ""
+	}
+
+	var (
+		buf    strings.Builder
+		target = make(map[string]bool)
+	)
+
+	buf.WriteString(" ON CONFLICT")
+	if op.ConflictTarget != "" {
+		columns := strings.Split(op.ConflictTarget, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+			target[columns[i]] = true
+			columns[i] = fmt.Sprintf("%q", columns[i])
+		}
+		fmt.Fprintf(&buf, " (%s)", strings.Join(columns, ", "))
+	}
 
-		fmt.Fprintf(&colBuf, colfmt, col.Name)
-		fmt.Fprintf(&valBuf, valfmt, val)
-		colPos++
+	var updates []string
+	for _, col := range row {
+		if !target[col.Name] {
+			updates = append(updates, fmt.Sprintf("%q = excluded.%q", col.Name, col.Name))
+		}
+	}
+
+	if op.OnDuplicate == activerecord.OnDuplicateSkip || len(updates) == 0 {
+		buf.WriteString(" DO NOTHING")
+	} else {
+		fmt.Fprintf(&buf, " DO UPDATE SET %s", strings.Join(updates, ", "))
 	}
+	return buf.String()
+}
 
-	const stmt = `INSERT INTO "%s" (%s) VALUES (%s)`
-	return fmt.Sprintf(stmt, op.TableName, colBuf.String(), valBuf.String()), nil
+// whereSQL returns the condition of predicates joined with AND operator.
+func whereSQL(predicates []activerecord.Predicate, args []interface{}) (string, []interface{}) {
+	conds := make([]string, 0, len(predicates))
+	for _, predicate := range predicates {
+		conds = append(conds, "("+predicate.Cond+")")
+		args = append(args, predicate.Args...)
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
 }
 
 func (s *DatabaseStatements) ExecInsert(ctx context.Context, op *activerecord.InsertOperation) (
 	id interface{}, err error,
 ) {
-	stmt, err := s.buildInsertStmt(op)
+	stmt, args, err := s.buildInsertStmt(op)
 	if err != nil {
 		return 0, err
 	}
 	fmt.Println(stmt)
 
-	result, err := s.Conn.ExecContext(ctx, stmt)
-	if err != nil {
-		return 0, err
+	if op.Returning != "" {
+		return s.execInsertReturning(ctx, stmt, args)
 	}
 
-	rows, err := result.RowsAffected()
+	result, err := Exec(ctx, s.Conn, stmt, args...)
 	if err != nil {
 		return 0, err
 	}
-	if rows != 1 {
-		return 0, fmt.Errorf("expected single row affected, got %d rows affected", rows)
+
+	// The number of rows inserted with multiple values or with the conflict
+	// clause is not known in advance.
+	if len(op.Rows) == 0 && op.OnDuplicate == "" {
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if rows != 1 {
+			return 0, fmt.Errorf("expected single row affected, got %d rows affected", rows)
+		}
 	}
 
 	return result.LastInsertId()
 }
 
-func (s *DatabaseStatements) buildUpdateStmt(op *activerecord.UpdateOperation) (string, error) {
+// execInsertReturning executes the insert statement and returns values of the
+// returned column of inserted rows.
+func (s *DatabaseStatements) execInsertReturning(
+	ctx context.Context, stmt string, args []interface{},
+) (interface{}, error) {
+	if activerecord.DryRunWriter(ctx) != nil {
+		_, err := Exec(ctx, s.Conn, stmt, args...)
+		return []interface{}(nil), err
+	}
+
+	rows, err := s.Conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []interface{}
+	for rows.Next() {
+		var value interface{}
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (s *DatabaseStatements) buildUpdateStmt(op *activerecord.UpdateOperation) (
+	string, []interface{}, error,
+) {
 	var (
-		stmtBuf strings.Builder
-		pk      interface{}
+		values []string
+		pk     interface{}
+		args   []interface{}
 	)
 
-	colNum := len(op.ColumnValues)
-	for colPos, col := range op.ColumnValues {
+	for _, col := range op.ColumnValues {
 		val, err := col.Type.Serialize(col.Value)
 		if err != nil {
-			return "", err
-		}
-
-		valfmt := `"%s" = '%v', `
-		if colPos == colNum-1 {
-			valfmt = `"%s" = '%v'`
+			return "", nil, err
 		}
 		if col.Name == op.PrimaryKey {
 			pk = val
 		}
 
-		fmt.Fprintf(&stmtBuf, valfmt, col.Name, val)
+		var valsql string
+		valsql, args = valueSQL(val, args)
+		values = append(values, fmt.Sprintf(`"%s" = %s`, col.Name, valsql))
+	}
+
+	for _, col := range op.Increments {
+		val, err := col.Type.Serialize(col.Value)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var valsql string
+		valsql, args = valueSQL(val, args)
+		values = append(values, fmt.Sprintf(`"%s" = COALESCE("%s", 0) + %s`, col.Name, col.Name, valsql))
 	}
 
-	const stmt = `UPDATE "%s" SET %s WHERE "%s" = '%v'`
-	return fmt.Sprintf(stmt, op.TableName, stmtBuf.String(), op.PrimaryKey, pk), nil
+	set := strings.Join(values, ", ")
+	if op.PrimaryKey == "" {
+		var where string
+		where, args = whereSQL(op.Where, args)
+		return fmt.Sprintf(`UPDATE "%s" SET %s%s`, op.TableName, set, where), args, nil
+	}
+
+	const stmt = `UPDATE "%s" SET %s WHERE "%s" = ?`
+	sql := fmt.Sprintf(stmt, op.TableName, set, op.PrimaryKey)
+	args = append(args, pk)
+
+	if op.LockingColumn != "" {
+		// Rows added before the locking column have no version, which
+		// is the same as the initial version.
+		sql += fmt.Sprintf(` AND COALESCE("%s", 0) = ?`, op.LockingColumn)
+		args = append(args, op.LockingValue)
+	}
+	return sql, args, nil
 }
 
 func (s *DatabaseStatements) ExecUpdate(
 	ctx context.Context, op *activerecord.UpdateOperation,
 ) error {
-	stmt, err := s.buildUpdateStmt(op)
+	stmt, args, err := s.buildUpdateStmt(op)
 	if err != nil {
 		return err
 	}
 	fmt.Println(stmt)
 
-	result, err := s.Conn.ExecContext(ctx, stmt)
+	result, err := Exec(ctx, s.Conn, stmt, args...)
 	if err != nil {
 		return err
 	}
 
+	// Any number of rows could be updated by the condition.
+	if op.PrimaryKey == "" {
+		return nil
+	}
+
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if rows == 0 && op.LockingColumn != "" {
+		err := &activerecord.ErrStaleObject{TableName: op.TableName, PrimaryKey: op.PrimaryKey}
+		for _, col := range op.ColumnValues {
+			if col.Name == op.PrimaryKey {
+				err.ID = col.Value
+			}
+		}
+		return err
+	}
 	if rows != 1 {
 		return fmt.Errorf("expected single row affected, got %d rows affected", rows)
 	}
@@ -125,9 +324,15 @@ func (s *DatabaseStatements) ExecUpdate(
 }
 
 func (s *DatabaseStatements) ExecDelete(ctx context.Context, op *activerecord.DeleteOperation) error {
-	const stmt = `DELETE FROM "%s" WHERE "%s" = '%v'`
-	sql := fmt.Sprintf(stmt, op.TableName, op.PrimaryKey, op.Value)
-	_, err := s.Conn.ExecContext(ctx, sql)
+	if op.PrimaryKey == "" {
+		where, args := whereSQL(op.Where, nil)
+		_, err := Exec(ctx, s.Conn, fmt.Sprintf(`DELETE FROM "%s"%s`, op.TableName, where), args...)
+		return err
+	}
+
+	const stmt = `DELETE FROM "%s" WHERE "%s" = ?`
+	sql := fmt.Sprintf(stmt, op.TableName, op.PrimaryKey)
+	_, err := Exec(ctx, s.Conn, sql, op.Value)
 	return err
 }
 
@@ -136,8 +341,13 @@ func (s *DatabaseStatements) ExecQuery(
 ) (
 	err error,
 ) {
-	fmt.Println(op.Text, op.Args)
-	rws, err := s.Conn.QueryContext(ctx, op.Text, op.Args...)
+	text := op.Text
+	if op.Lock != "" {
+		text += " " + string(op.Lock)
+	}
+
+	fmt.Println(text, op.Args)
+	rws, err := s.Conn.QueryContext(ctx, text, op.Args...)
 	if err != nil {
 		return err
 	}
@@ -171,11 +381,32 @@ func (s *DatabaseStatements) ExecQuery(
 	return nil
 }
 
+// JSONExtract returns the SQL/JSON expression of the value at the path of keys
+// within the JSON column.
+func (s *DatabaseStatements) JSONExtract(column string, path ...string) string {
+	return fmt.Sprintf("JSON_VALUE(%q, %s)", column, JSONPath(path...))
+}
+
+// JSONPath returns a quoted SQL/JSON path of the keys, e.g. '$."a"."b"'.
+func JSONPath(path ...string) string {
+	var buf strings.Builder
+	buf.WriteString("$")
+	for _, key := range path {
+		fmt.Fprintf(&buf, ".%q", key)
+	}
+	return "'" + strings.ReplaceAll(buf.String(), "'", "''") + "'"
+}
+
 type SchemaStatements struct {
 	Conn ConnectionStatements
 }
 
 func (s *SchemaStatements) ColumnType(typeName string) (activerecord.Type, error) {
+	// Types registered by the application take precedence over built-in types.
+	if t, ok := activerecord.LookupType(typeName); ok {
+		return t, nil
+	}
+
 	switch strings.ToLower(typeName) {
 	case "integer":
 		return new(activerecord.Int64), nil
@@ -191,17 +422,119 @@ func (s *SchemaStatements) ColumnType(typeName string) (activerecord.Type, error
 		return new(activerecord.Date), nil
 	case "time":
 		return new(activerecord.Time), nil
+	case "decimal", "numeric":
+		return new(activerecord.Decimal), nil
+	case "uuid":
+		return new(activerecord.UUID), nil
+	case "blob", "binary", "bytea":
+		return new(activerecord.Binary), nil
+	case "json", "jsonb":
+		return new(activerecord.JSON), nil
 	default:
 		return nil, activerecord.ErrUnsupportedType{TypeName: typeName}
 	}
 }
 
+func (s *SchemaStatements) Indexes(ctx context.Context, tableName string) (
+	[]activerecord.IndexDefinition, error,
+) {
+	return nil, errors.New("ansi: not supported")
+}
+
+func (s *SchemaStatements) ForeignKeys(ctx context.Context, tableName string) (
+	[]activerecord.ForeignKeyDefinition, error,
+) {
+	return nil, errors.New("ansi: not supported")
+}
+
+func (s *SchemaStatements) Tables(ctx context.Context) ([]string, error) {
+	return nil, errors.New("ansi: not supported")
+}
+
+func (s *SchemaStatements) Execute(ctx context.Context, stmt string) error {
+	_, err := Exec(ctx, s.Conn, stmt)
+	return err
+}
+
 func (s *SchemaStatements) ColumnDefinitions(ctx context.Context, tableName string) (
 	[]activerecord.ColumnDefinition, error,
 ) {
 	return nil, errors.New("ansi: not supported")
 }
 
+// ColumnTypeSQL returns a native type of the column including the limit or
+// precision and scale, e.g. "VARCHAR(255)".
+func ColumnTypeSQL(column activerecord.ColumnDefinition) string {
+	nativeType := column.Type.NativeType()
+	switch {
+	case column.Limit > 0:
+		return fmt.Sprintf("%s(%d)", nativeType, column.Limit)
+	case column.Precision > 0 && column.Scale > 0:
+		return fmt.Sprintf("%s(%d,%d)", nativeType, column.Precision, column.Scale)
+	case column.Precision > 0:
+		return fmt.Sprintf("%s(%d)", nativeType, column.Precision)
+	default:
+		return nativeType
+	}
+}
+
+// LiteralSQL returns a literal of the value used within statements.
+func LiteralSQL(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if value {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return fmt.Sprint(value)
+	case []byte:
+		return fmt.Sprintf("X'%X'", value)
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(value), "'", "''") + "'"
+	}
+}
+
+// ColumnSQL returns a definition of the column used in CREATE TABLE and
+// ALTER TABLE statements.
+// defaultSQL returns the default value of the column used within statements,
+// or an empty string, when the column has no default value. Literal values
+// are serialized with the column type.
+func defaultSQL(column activerecord.ColumnDefinition) string {
+	if column.DefaultSQL != "" {
+		return "(" + column.DefaultSQL + ")"
+	}
+	if column.Default == nil {
+		return ""
+	}
+	value, err := column.Type.Serialize(column.Default)
+	if err != nil {
+		value = column.Default
+	}
+	return LiteralSQL(value)
+}
+
+func ColumnSQL(column activerecord.ColumnDefinition) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `%q %s`, column.Name, ColumnTypeSQL(column))
+
+	if column.NotNull {
+		buf.WriteString(" NOT NULL")
+	}
+
+	if def := defaultSQL(column); def != "" {
+		fmt.Fprintf(&buf, " DEFAULT %s", def)
+	}
+
+	if column.Check != "" {
+		fmt.Fprintf(&buf, " CHECK (%s)", column.Check)
+	}
+	return buf.String()
+}
+
 func (s *SchemaStatements) CreateTable(ctx context.Context, table *activerecord.Table) error {
 	var buf strings.Builder
 	fmt.Fprintf(&buf, `CREATE TABLE %q (`, table.Name())
@@ -209,36 +542,155 @@ func (s *SchemaStatements) CreateTable(ctx context.Context, table *activerecord.
 	var primaryKey string
 
 	for _, column := range table.Columns() {
-		nativeType := column.Type.NativeType()
-
-		if column.NotNull {
-			nativeType += " NOT NULL"
-		}
 		if column.IsPrimaryKey {
 			primaryKey = column.Name
 		}
-
-		fmt.Fprintf(&buf, `%s %s, `, column.Name, nativeType)
+		fmt.Fprintf(&buf, `%s, `, ColumnSQL(column))
 	}
 
-	for _, target := range table.ForeignKeys() {
-		fk := fmt.Sprintf("%s_id", strings.TrimSuffix(target, "s"))
-		fmt.Fprintf(&buf, `FOREIGN KEY (%q) REFERENCES "%s" ("id"), `, fk, target)
+	for _, fk := range table.ForeignKeys() {
+		fmt.Fprintf(&buf, `%s, `, ForeignKeySQL(fk))
 	}
 
 	fmt.Fprintf(&buf, `PRIMARY KEY ("%s"))`, primaryKey)
-	_, err := s.Conn.ExecContext(ctx, buf.String())
+	if _, err := Exec(ctx, s.Conn, buf.String()); err != nil {
+		return err
+	}
+
+	for _, index := range table.Indexes() {
+		if err := s.AddIndex(ctx, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SchemaStatements) DropTable(ctx context.Context, tableName string) error {
+	_, err := Exec(ctx, s.Conn, fmt.Sprintf(`DROP TABLE %q`, tableName))
+	return err
+}
+
+func (s *SchemaStatements) AddColumn(
+	ctx context.Context, tableName string, column activerecord.ColumnDefinition,
+) error {
+	const stmt = `ALTER TABLE %q ADD COLUMN %s`
+	_, err := Exec(ctx, s.Conn, fmt.Sprintf(stmt, tableName, ColumnSQL(column)))
+	return err
+}
+
+func (s *SchemaStatements) RemoveColumn(ctx context.Context, tableName, columnName string) error {
+	const stmt = `ALTER TABLE %q DROP COLUMN %q`
+	_, err := Exec(ctx, s.Conn, fmt.Sprintf(stmt, tableName, columnName))
 	return err
 }
 
-func (s *SchemaStatements) AddForeignKey(ctx context.Context, owner, target string) error {
+func (s *SchemaStatements) RenameColumn(
+	ctx context.Context, tableName, columnName, newColumnName string,
+) error {
+	const stmt = `ALTER TABLE %q RENAME COLUMN %q TO %q`
+	_, err := Exec(ctx, s.Conn, fmt.Sprintf(stmt, tableName, columnName, newColumnName))
+	return err
+}
+
+func (s *SchemaStatements) ChangeColumn(
+	ctx context.Context, tableName string, column activerecord.ColumnDefinition,
+) error {
+	stmts := []string{
+		fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE %s`,
+			tableName, column.Name, ColumnTypeSQL(column)),
+	}
+	if def := defaultSQL(column); def != "" {
+		stmts = append(stmts, fmt.Sprintf(
+			`ALTER TABLE %q ALTER COLUMN %q SET DEFAULT %s`,
+			tableName, column.Name, def))
+	} else {
+		stmts = append(stmts, fmt.Sprintf(
+			`ALTER TABLE %q ALTER COLUMN %q DROP DEFAULT`, tableName, column.Name))
+	}
+	if column.NotNull {
+		stmts = append(stmts, fmt.Sprintf(
+			`ALTER TABLE %q ALTER COLUMN %q SET NOT NULL`, tableName, column.Name))
+	} else {
+		stmts = append(stmts, fmt.Sprintf(
+			`ALTER TABLE %q ALTER COLUMN %q DROP NOT NULL`, tableName, column.Name))
+	}
+
+	for _, stmt := range stmts {
+		if _, err := Exec(ctx, s.Conn, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForeignKeySQL returns a definition of the foreign key constraint used in
+// CREATE TABLE and ALTER TABLE statements.
+func ForeignKeySQL(fk activerecord.ForeignKeyDefinition) string {
 	var buf strings.Builder
+	if fk.Name != "" {
+		fmt.Fprintf(&buf, `CONSTRAINT %q `, fk.Name)
+	}
 
-	fk := fmt.Sprintf("%s_id", strings.TrimSuffix(target, "s"))
-	fmt.Fprintf(&buf, `ALTER TABLE %q ADD CONSTRAINT fk_%s_on_%s `, owner, owner, target)
+	fmt.Fprintf(&buf, `FOREIGN KEY (%q) REFERENCES %q (%q)`,
+		fk.Column, fk.ReferencedTable, fk.PrimaryKey)
 
-	// TODO: id is not necessary a primary key.
-	fmt.Fprintf(&buf, `FOREIGN KEY (%q) REFERENCES %q ("id")"`, fk, target)
-	_, err := s.Conn.ExecContext(ctx, buf.String())
+	if fk.OnDelete != "" && fk.OnDelete != activerecord.NoAction {
+		buf.WriteString(" ON DELETE " + string(fk.OnDelete))
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != activerecord.NoAction {
+		buf.WriteString(" ON UPDATE " + string(fk.OnUpdate))
+	}
+	return buf.String()
+}
+
+// IndexSQL returns CREATE INDEX statement of the index.
+func IndexSQL(index activerecord.IndexDefinition) string {
+	var buf strings.Builder
+
+	buf.WriteString("CREATE ")
+	if index.Unique {
+		buf.WriteString("UNIQUE ")
+	}
+
+	columns := make([]string, 0, len(index.Columns))
+	for _, column := range index.Columns {
+		// Expressions are wrapped in parentheses and used as is.
+		if strings.HasPrefix(column, "(") {
+			columns = append(columns, column)
+			continue
+		}
+		columns = append(columns, fmt.Sprintf("%q", column))
+	}
+
+	fmt.Fprintf(&buf, `INDEX %q ON %q (%s)`, index.Name, index.TableName, strings.Join(columns, ", "))
+	if index.Where != "" {
+		fmt.Fprintf(&buf, ` WHERE %s`, index.Where)
+	}
+	return buf.String()
+}
+
+func (s *SchemaStatements) AddIndex(ctx context.Context, index activerecord.IndexDefinition) error {
+	_, err := Exec(ctx, s.Conn, IndexSQL(index))
+	return err
+}
+
+func (s *SchemaStatements) RemoveIndex(ctx context.Context, tableName, indexName string) error {
+	_, err := Exec(ctx, s.Conn, fmt.Sprintf(`DROP INDEX %q`, indexName))
+	return err
+}
+
+func (s *SchemaStatements) AddForeignKey(
+	ctx context.Context, fk activerecord.ForeignKeyDefinition,
+) error {
+	stmt := fmt.Sprintf(`ALTER TABLE %q ADD %s`, fk.TableName, ForeignKeySQL(fk))
+	_, err := Exec(ctx, s.Conn, stmt)
+	return err
+}
+
+func (s *SchemaStatements) RemoveForeignKey(
+	ctx context.Context, fk activerecord.ForeignKeyDefinition,
+) error {
+	stmt := fmt.Sprintf(`ALTER TABLE %q DROP CONSTRAINT %q`, fk.TableName, fk.Name)
+	_, err := Exec(ctx, s.Conn, stmt)
 	return err
 }
@@ -1,6 +1,7 @@
 package activerecord
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -68,6 +69,10 @@ type CollectionAccessors interface {
 type AssociationReflection struct {
 	*Relation
 	Association
+
+	// Name is the name of the association within the owner, e.g. "books"
+	// for the association defined with R.HasMany("books").
+	Name string
 }
 
 type BelongsTo struct {
@@ -75,6 +80,9 @@ type BelongsTo struct {
 	reflection *Reflection
 	targetName string
 	foreignKey string
+
+	counterCache       bool
+	counterCacheColumn string
 }
 
 func (a *BelongsTo) AssociationOwner() *Relation {
@@ -94,6 +102,47 @@ func (a *BelongsTo) ForeignKey(fk string) {
 	a.foreignKey = fk
 }
 
+// CounterCache caches the number of owner records in the column of the target
+// record. By default the column name is the plural name of the owner with
+// "_count" suffix.
+//
+// So a relation "book" that defines a BelongsTo("author") association with a
+// counter cache keeps the number of books in "authors"."books_count".
+func (a *BelongsTo) CounterCache(columnName string) {
+	a.counterCache = true
+	a.counterCacheColumn = columnName
+}
+
+// CounterCacheColumn returns the name of the counter cache column of the
+// target, or an empty string, when the counter is not cached.
+func (a *BelongsTo) CounterCacheColumn() string {
+	if !a.counterCache {
+		return ""
+	}
+	if a.counterCacheColumn != "" {
+		return a.counterCacheColumn
+	}
+	// TODO: Define library methods to pluralize words.
+	return strings.ToLower(a.owner.Name()) + "s_count"
+}
+
+// updateCounter changes the counter cache of the target by delta atomically.
+func (a *BelongsTo) updateCounter(ctx context.Context, targetID interface{}, delta int) error {
+	column := a.CounterCacheColumn()
+	if column == "" || targetID == nil || delta == 0 {
+		return nil
+	}
+
+	targets, err := a.reflection.Reflection(a.targetName)
+	if err != nil {
+		return err
+	}
+
+	// Counters of softly deleted targets are maintained as well.
+	targets = targets.WithContext(ctx).Unscoped()
+	return targets.Where(targets.PrimaryKey(), targetID).UpdateCounters(map[string]int{column: delta})
+}
+
 func (a *BelongsTo) AssociationForeignKey() string {
 	if a.foreignKey != "" {
 		return a.foreignKey
@@ -142,6 +191,24 @@ func (a *BelongsTo) String() string {
 	return fmt.Sprintf("#<Association type: 'belongs_to', name: '%s'>", a.targetName)
 }
 
+// CounterCache returns an initializer of BelongsTo association, which caches
+// the number of owner records in the column of the target (see
+// BelongsTo.CounterCache).
+//
+//	activerecord.New("book", func(r *activerecord.R) {
+//		r.BelongsTo("author", activerecord.CounterCache())
+//	})
+func CounterCache(columnName ...string) func(*BelongsTo) {
+	switch len(columnName) {
+	case 0:
+		return func(a *BelongsTo) { a.CounterCache("") }
+	case 1:
+		return func(a *BelongsTo) { a.CounterCache(columnName[0]) }
+	default:
+		panic(&ErrMultipleVariadicArguments{Name: "columnName"})
+	}
+}
+
 type HasMany struct {
 	owner      *Relation
 	reflection *Reflection
@@ -153,6 +220,31 @@ func (a *HasMany) AssociationName() string {
 	return a.targetName
 }
 
+// CounterCacheColumn returns the name of the owner column, which caches the
+// number of targets, or an empty string, when the number is not cached.
+func (a *HasMany) CounterCacheColumn() string {
+	assoc := a.inverseOf()
+	if assoc == nil {
+		return ""
+	}
+	return assoc.CounterCacheColumn()
+}
+
+// inverseOf returns the BelongsTo association of the target referencing the
+// owner, or nil, when there is no such association.
+func (a *HasMany) inverseOf() *BelongsTo {
+	targets, err := a.reflection.Reflection(a.targetName)
+	if err != nil {
+		return nil
+	}
+	for _, assoc := range targets.associations.keys {
+		if assoc, ok := assoc.(*BelongsTo); ok && assoc.targetName == a.owner.Name() {
+			return assoc
+		}
+	}
+	return nil
+}
+
 func (a *HasMany) AssociationForeignKey() string {
 	// TODO: this is completely wrong.
 	if a.foreignKey != "" {
@@ -189,33 +281,76 @@ func (a *HasMany) AccessCollection(owner *ActiveRecord) CollectionResult {
 
 	targets = targets.WithContext(owner.Context())
 
-	// TODO: Make "scope" accessable and understandable.
+	// The collection is a relation of the target model, so named and default
+	// scopes of the target model are applied to the collection as well.
 	targets = targets.Where(a.AssociationForeignKey(), owner.ID())
 	return CollectionResult{Ok(targets)}
 }
 
+// AssignCollection replaces targets of the owner with the given targets
+// within a single transaction. Targets are matched by identifiers: existing
+// targets missing in the given ones are deleted, persisted targets are
+// updated and new targets are inserted.
 func (a *HasMany) AssignCollection(owner *ActiveRecord, targets ...*ActiveRecord) RecordResult {
-	// Perform very naive approach delete existing targets and set new targets.
-	err := owner.Collection(a.targetName + "s").DeleteAll()
+	rel, err := a.reflection.Reflection(a.targetName)
 	if err != nil {
 		return ErrRecord(err)
 	}
+	rel = rel.WithContext(owner.Context())
 
-	for i := 0; i < len(targets); i++ {
-		// TODO: Ensure each target record is an instance of the association's owner.
-
-		// Put a reference of the owner (owner_id) to the target record.
-		err = targets[i].AssignAttribute(a.AssociationForeignKey(), owner.ID())
+	err = rel.connections.Transaction(owner.Context(), rel.connectionName, func() error {
+		existing, err := owner.Collection(a.targetName + "s").ToA()
 		if err != nil {
-			return ErrRecord(err)
+			return err
 		}
 
-		_, err = targets[i].WithContext(owner.Context()).Insert()
-		if err != nil {
-			return ErrRecord(err)
+		assigned := make(map[interface{}]bool, len(targets))
+		for _, target := range targets {
+			if target.ID() != nil {
+				assigned[target.ID()] = true
+			}
+		}
+
+		for _, rec := range existing {
+			if assigned[rec.ID()] {
+				continue
+			}
+			if _, err = rec.Delete(); err != nil {
+				return err
+			}
 		}
-	}
 
+		for _, target := range targets {
+			// TODO: Ensure each target record is an instance of the association's owner.
+
+			// Write the target through the connection of the transaction.
+			rec := target.WithContext(owner.Context())
+			rec.conn = rel.Connection()
+
+			// Put a reference of the owner (owner_id) to the target record.
+			err = rec.AssignAttribute(a.AssociationForeignKey(), owner.ID())
+			if err != nil {
+				return err
+			}
+
+			// Persisted targets of other owners are moved to this owner.
+			if rec.ID() != nil {
+				_, err = rec.Update()
+			} else {
+				_, err = rec.Insert()
+			}
+			if err != nil {
+				return err
+			}
+
+			target.attributes = rec.attributes.copy()
+			target.init()
+		}
+		return nil
+	})
+	if err != nil {
+		return ErrRecord(err)
+	}
 	return OkRecord(owner)
 }
 
@@ -435,19 +570,19 @@ func (a *associations) ReflectOnAssociation(assocName string) *AssociationReflec
 	if err != nil {
 		return nil
 	}
-	return &AssociationReflection{Relation: rel, Association: a.keys[assocName]}
+	return &AssociationReflection{Relation: rel, Association: a.keys[assocName], Name: assocName}
 }
 
 // ReflectOnAllAssociations returns an array of AssociationReflection types for all
 // associations in the Relation.
 func (a *associations) ReflectOnAllAssociations() []*AssociationReflection {
 	arefs := make([]*AssociationReflection, 0, len(a.keys))
-	for _, assoc := range a.keys {
+	for name, assoc := range a.keys {
 		rel, _ := a.reflection.Reflection(assoc.AssociationName())
 		if rel == nil {
 			continue
 		}
-		arefs = append(arefs, &AssociationReflection{Relation: rel, Association: assoc})
+		arefs = append(arefs, &AssociationReflection{Relation: rel, Association: assoc, Name: name})
 	}
 	return arefs
 }
@@ -143,3 +143,100 @@ func TestActiveRecord_BelongsTo_AssignAssociation(t *testing.T) {
 	target.Expect("failed to update owner of the target")
 	t.Log(target)
 }
+
+func TestActiveRecord_BelongsTo_CounterCache(t *testing.T) {
+	EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+
+	defer os.Remove(t.Name())
+	defer RemoveConnection("primary")
+
+	Migrate(t.Name(), func(m *M) {
+		m.CreateTable("owners", func(t *Table) {
+			t.String("name")
+			t.Int64("targets_count", NotNull(), Default(0))
+		})
+		m.CreateTable("targets", func(t *Table) {
+			t.Int64("value")
+			t.DateTime("deleted_at")
+			t.References("owners")
+		})
+	})
+
+	Owner := New("owner", func(r *R) { r.HasMany("targets") })
+	Target := New("target", func(r *R) {
+		r.BelongsTo("owner", CounterCache())
+		r.SoftDelete("deleted_at")
+	})
+
+	require.Equal(t, "targets_count", Owner.ReflectOnAssociation("targets").Association.(*HasMany).CounterCacheColumn())
+
+	counter := func(owner *ActiveRecord) interface{} {
+		return Owner.Find(owner.ID()).Unwrap().Attribute("targets_count")
+	}
+
+	first := Owner.Create(Hash{"name": "First"}).Unwrap()
+	second := Owner.Create(Hash{"name": "Second"}).Unwrap()
+
+	target := Target.Create(Hash{"value": 1, "owner_id": first.ID()}).Unwrap()
+	_, err := Target.InsertAll(
+		Hash{"value": 2, "owner_id": first.ID()},
+		Hash{"value": 3, "owner_id": second.ID()},
+	)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), counter(first))
+	require.Equal(t, int64(1), counter(second))
+
+	// Stale counters of owners are not written back on update.
+	_, err = first.Update()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), counter(first))
+
+	// Counters are moved to the new owner on reassignment.
+	require.NoError(t, OkRecord(target).AssignAssociation("owner", OkRecord(second)).Err())
+	require.Equal(t, int64(1), counter(first))
+	require.Equal(t, int64(2), counter(second))
+
+	_, err = target.Delete()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter(second))
+
+	// Softly deleted records are not counted twice.
+	_, err = target.HardDelete()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), counter(second))
+
+	require.NoError(t, Target.All().DeleteAll())
+	require.Equal(t, int64(0), counter(first))
+	require.Equal(t, int64(0), counter(second))
+
+	// Counters are recounted after operations bypassing counter caches.
+	err = Target.UpsertAll([]map[string]interface{}{
+		{"id": 10, "value": 4, "owner_id": first.ID()},
+		{"id": 11, "value": 5, "owner_id": first.ID()},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), counter(first))
+
+	// Counters of owners without targets are reset as well.
+	require.NoError(t, Owner.Where("id", second.ID()).UpdateAll(Hash{"targets_count": 5}))
+
+	require.NoError(t, Owner.ResetCounters("targets"))
+	require.Equal(t, int64(2), counter(first))
+	require.Equal(t, int64(0), counter(second))
+
+	require.Error(t, Target.ResetCounters("owner"))
+
+	// Records are not written, when counters could not be changed.
+	Migrate(t.Name()+"_remove_counter", func(m *M) {
+		m.RemoveColumn("owners", "targets_count")
+	})
+
+	err = Target.Create(Hash{"value": 6, "owner_id": first.ID()}).Err()
+	require.Error(t, err)
+
+	targets, err := Target.Where("value", 6).ToA()
+	require.NoError(t, err)
+	require.Empty(t, targets)
+}
@@ -44,6 +44,65 @@ func (p PrimaryKey) PrimaryKey() bool {
 	return true
 }
 
+// virtual must implement attributes that are not persisted.
+type virtual interface {
+	Virtual() bool
+}
+
+// VirtualAttribute makes any specified attribute virtual, values of virtual
+// attributes are not read from and not written to the database.
+//
+// When Compute is set, the attribute is read-only and its value is computed
+// from the record on access.
+type VirtualAttribute struct {
+	Attribute
+	Compute func(*ActiveRecord) interface{}
+}
+
+// Virtual always returns true.
+func (v VirtualAttribute) Virtual() bool {
+	return true
+}
+
+// IsComputed returns true when the value of the attribute is computed.
+func (v VirtualAttribute) IsComputed() bool {
+	return v.Compute != nil
+}
+
+// isVirtual returns true when the attribute is not persisted.
+func isVirtual(attr Attribute) bool {
+	v, ok := attr.(virtual)
+	return ok && v.Virtual()
+}
+
+// StoreAttribute is an accessor of the key within the JSON attribute, values of
+// the store attributes are read from and written to the Store attribute.
+type StoreAttribute struct {
+	Attribute
+	Store string
+}
+
+// Virtual always returns true.
+func (s StoreAttribute) Virtual() bool {
+	return true
+}
+
+// AttributeOption configures virtual attributes.
+type AttributeOption func(*VirtualAttribute)
+
+// Virtual makes the attribute computed by the given function.
+//
+//	User := activerecord.New("user", func(r *activerecord.R) {
+//		r.Attribute("full_name", new(activerecord.String), activerecord.Virtual(
+//			func(u *activerecord.ActiveRecord) interface{} {
+//				return fmt.Sprintf("%s %s", u.Attribute("first_name"), u.Attribute("last_name"))
+//			},
+//		))
+//	})
+func Virtual(fn func(*ActiveRecord) interface{}) AttributeOption {
+	return func(v *VirtualAttribute) { v.Compute = fn }
+}
+
 type attr struct {
 	Name string
 	Type Type
@@ -69,6 +128,18 @@ func (e *ErrUnknownAttribute) Error() string {
 	return fmt.Sprintf("unknown attribute %q for %s", e.Attr, e.RecordName)
 }
 
+// ErrReadOnlyAttribute is returned on attempt to assign computed attribute of
+// the ActiveRecord.
+type ErrReadOnlyAttribute struct {
+	RecordName string
+	Attr       string
+}
+
+// Error returns a string representation of the error.
+func (e *ErrReadOnlyAttribute) Error() string {
+	return fmt.Sprintf("attribute %q of %s is read-only", e.Attr, e.RecordName)
+}
+
 const (
 	// default name of the primary key.
 	defaultPrimaryKeyName = "id"
@@ -193,15 +264,22 @@ func (a *attributes) AttributeNames() []string {
 	return names
 }
 
+// ColumnNames returns qualified names of the persisted attributes.
 func (a *attributes) ColumnNames() []string {
 	names := make([]string, 0, len(a.keys))
-	for name := range a.keys {
-		names = append(names, a.recordName+"s."+name)
+	for name, attr := range a.keys {
+		if !isVirtual(attr) {
+			names = append(names, a.columnName(name))
+		}
 	}
 	sort.StringSlice(names).Sort()
 	return names
 }
 
+func (a *attributes) columnName(attrName string) string {
+	return a.recordName + "s." + attrName
+}
+
 // HasAttribute returns true if the given table attribute is in the attribute map,
 // otherwise false.
 func (a *attributes) HasAttribute(attrName string) bool {
@@ -225,6 +303,12 @@ func (a *attributes) AssignAttribute(attrName string, val interface{}) error {
 	if !a.HasAttribute(attrName) {
 		return &ErrUnknownAttribute{RecordName: a.recordName, Attr: attrName}
 	}
+	if v, ok := a.keys[attrName].(VirtualAttribute); ok && v.IsComputed() {
+		return &ErrReadOnlyAttribute{RecordName: a.recordName, Attr: attrName}
+	}
+	if s, ok := a.keys[attrName].(StoreAttribute); ok {
+		return a.assignStore(s, val)
+	}
 	// TODO: Ensure that attribute passes validation?
 	// if err := attr.Validate(val); err != nil {
 	// 	return err
@@ -237,6 +321,31 @@ func (a *attributes) AssignAttribute(attrName string, val interface{}) error {
 	return nil
 }
 
+// assignStore assigns the value of the key within the JSON store attribute.
+// The store is copied, so records do not share the same store.
+func (a *attributes) assignStore(s StoreAttribute, val interface{}) error {
+	store := make(activesupport.Hash)
+	if value := a.values[s.Store]; value != nil {
+		hash, err := new(JSON).Deserialize(value)
+		if err != nil {
+			return err
+		}
+		store = hash.(activesupport.Hash).Copy()
+	}
+
+	value, err := s.AttributeType().Deserialize(val)
+	if err != nil {
+		return ErrInvalidType{AttrName: s.AttributeName(), TypeName: s.AttributeType().String(), Value: val}
+	}
+	store[s.AttributeName()] = value
+
+	if a.values == nil {
+		a.values = make(activesupport.Hash)
+	}
+	a.values[s.Store] = store
+	return nil
+}
+
 // AssignAttributes allows to set all the attributes by passing in a map of attributes
 // with keys matching attributet names.
 //
@@ -250,7 +359,15 @@ func (a *attributes) AssignAttributes(newAttributes map[string]interface{}) erro
 		values = a.values.Copy()
 	)
 
+	// Store attributes are assigned after the stores themselves, so the keys
+	// are not overwritten by the assignment of a store.
+	var stored []string
+
 	for attrName, val := range newAttributes {
+		if _, ok := a.keys[attrName].(StoreAttribute); ok {
+			stored = append(stored, attrName)
+			continue
+		}
 		err := a.AssignAttribute(attrName, val)
 		if err != nil {
 			// Return the original state of the attributes.
@@ -259,6 +376,14 @@ func (a *attributes) AssignAttributes(newAttributes map[string]interface{}) erro
 			return err
 		}
 	}
+	for _, attrName := range stored {
+		err := a.AssignAttribute(attrName, newAttributes[attrName])
+		if err != nil {
+			a.keys = keys
+			a.values = values
+			return err
+		}
+	}
 	return nil
 }
 
@@ -267,6 +392,18 @@ func (a *attributes) AccessAttribute(attrName string) (val interface{}) {
 	if !a.HasAttribute(attrName) {
 		return nil
 	}
+	if s, ok := a.keys[attrName].(StoreAttribute); ok {
+		store, err := new(JSON).Deserialize(a.values[s.Store])
+		if err != nil {
+			return nil
+		}
+		// Values of other types written to the store directly are ignored.
+		value, err := s.AttributeType().Deserialize(store.(activesupport.Hash)[attrName])
+		if err != nil {
+			return nil
+		}
+		return value
+	}
 	return a.values[attrName]
 }
 
@@ -281,7 +418,7 @@ func (a *attributes) AttributePresent(attrName string) bool {
 	if !a.HasAttribute(attrName) {
 		return false
 	}
-	return a.values[attrName] != nil
+	return a.AccessAttribute(attrName) != nil
 }
 
 func (a *attributes) AttributeForInspect(attrName string) Attribute {
@@ -2,24 +2,29 @@ package activerecord
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/activegraph/activegraph/activesupport"
 	"github.com/activegraph/activegraph/internal"
 )
 
 const (
 	primaryConnectionName = "primary"
+
+	// defaultStickiness is a duration after the write operation, during which
+	// all queries are routed to the writing database.
+	defaultStickiness = 2 * time.Second
 )
 
 var (
-	globalConnectionHandler *connectionHandler
+	globalConnectionHandler = newConnectionHandler(newConnectionAdapters())
 )
 
-func init() {
-	globalConnectionHandler = newConnectionHandler()
-}
-
 // ErrAdapterNotFound is returned when Active Record cannot find database
 // specified database adapter.
 type ErrAdapterNotFound struct {
@@ -40,6 +45,39 @@ func (e *ErrConnectionNotEstablished) Error() string {
 	return fmt.Sprintf("connection %q has not been established", e.Name)
 }
 
+// DatabaseRole defines the role of the database connection.
+type DatabaseRole string
+
+const (
+	// RoleWriting is a role of the database that accepts all operations.
+	RoleWriting DatabaseRole = "writing"
+	// RoleReading is a role of the database replica that accepts only queries.
+	RoleReading DatabaseRole = "reading"
+)
+
+type roleContextKey struct{}
+
+// ConnectedTo returns a copy of the context with the specified database role.
+// Queries executed with RoleReading context are always routed to the replica,
+// queries executed with RoleWriting context are always routed to the writer.
+//
+//	ctx := activerecord.ConnectedTo(ctx, activerecord.RoleReading)
+//	books, err := Book.WithContext(ctx).All().ToA()
+func ConnectedTo(ctx context.Context, role DatabaseRole) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+func roleFromContext(ctx context.Context) DatabaseRole {
+	role, _ := ctx.Value(roleContextKey{}).(DatabaseRole)
+	return role
+}
+
+// DatabaseConfig describes a database connection. Connections are identified
+// by Name, when the name is empty the connection becomes the "primary" one.
+//
+// A connection with RoleReading role is a replica of the connection with the
+// same name, all insert, update, delete operations and transactions are still
+// executed by the writing connection.
 type DatabaseConfig struct {
 	Name     string
 	Adapter  string
@@ -47,43 +85,227 @@ type DatabaseConfig struct {
 	Username string
 	Password string
 	Database string
+
+	Role DatabaseRole
+
+	// MaxOpenConns is the maximum number of open connections to the database,
+	// zero means unlimited.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of connections in the idle connection
+	// pool, zero means the default of the database/sql package.
+	MaxIdleConns int
+	// ConnMaxIdleTime is the maximum amount of time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+	// ConnMaxLifetime is the maximum amount of time a connection may be reused.
+	ConnMaxLifetime time.Duration
+
+	// BusyTimeout is the amount of time to wait for the locked database before
+	// returning an error. Supported only by SQLite adapter.
+	BusyTimeout time.Duration
+	// JournalMode is the journal mode of the database, e.g. "WAL". Supported
+	// only by SQLite adapter.
+	JournalMode string
+
+	// Stickiness is a duration after the write operation, during which queries
+	// are routed to the writing database to read your own writes. Applies only
+	// to the replica configuration, defaults to 2 seconds.
+	Stickiness time.Duration
+}
+
+// replica is a reading database connection.
+type replica struct {
+	conn       Conn
+	stickiness time.Duration
+	lastWrite  int64
+}
+
+// replicatedConn routes queries to the replica, while the rest of operations
+// are executed by the writing connection.
+type replicatedConn struct {
+	Conn
+	replica *replica
+}
+
+func (c *replicatedConn) written() {
+	atomic.StoreInt64(&c.replica.lastWrite, time.Now().UnixNano())
+}
+
+func (c *replicatedConn) isSticky() bool {
+	lastWrite := atomic.LoadInt64(&c.replica.lastWrite)
+	return time.Since(time.Unix(0, lastWrite)) < c.replica.stickiness
+}
+
+func (c *replicatedConn) ExecInsert(ctx context.Context, op *InsertOperation) (
+	interface{}, error,
+) {
+	defer c.written()
+	return c.Conn.ExecInsert(ctx, op)
+}
+
+func (c *replicatedConn) ExecUpdate(ctx context.Context, op *UpdateOperation) error {
+	defer c.written()
+	return c.Conn.ExecUpdate(ctx, op)
+}
+
+func (c *replicatedConn) ExecDelete(ctx context.Context, op *DeleteOperation) error {
+	defer c.written()
+	return c.Conn.ExecDelete(ctx, op)
+}
+
+func (c *replicatedConn) BeginTransaction(ctx context.Context) (Conn, error) {
+	defer c.written()
+	return c.Conn.BeginTransaction(ctx)
+}
+
+func (c *replicatedConn) ExecQuery(
+	ctx context.Context, op *QueryOperation, cb func(activesupport.Hash) bool,
+) error {
+	// Rows could be locked only by the writing database.
+	if op.Lock != "" {
+		return c.Conn.ExecQuery(ctx, op, cb)
+	}
+
+	switch roleFromContext(ctx) {
+	case RoleWriting:
+		return c.Conn.ExecQuery(ctx, op, cb)
+	case RoleReading:
+		return c.replica.conn.ExecQuery(ctx, op, cb)
+	}
+	if c.isSticky() {
+		return c.Conn.ExecQuery(ctx, op, cb)
+	}
+	return c.replica.conn.ExecQuery(ctx, op, cb)
+}
+
+type lockContextKey struct{}
+
+// ContextWithLock returns a copy of the context with the specified lock mode.
+// Transactions started with such context are expected to lock rows in the given
+// mode, adapters without row-level locks could lock the whole database instead.
+//
+// SQLite adapter starts such transactions with BEGIN IMMEDIATE, locking queries
+// (see Relation.Lock) are allowed only within them.
+func ContextWithLock(ctx context.Context, mode LockMode) context.Context {
+	return context.WithValue(ctx, lockContextKey{}, mode)
+}
+
+// LockFromContext returns the lock mode of the context, or empty string when
+// the lock mode is not specified.
+func LockFromContext(ctx context.Context) LockMode {
+	mode, _ := ctx.Value(lockContextKey{}).(LockMode)
+	return mode
+}
+
+type dryRunContextKey struct{}
+
+// DryRun returns a copy of the context, where statements changing the database
+// are written to the writer instead of being executed.
+//
+//	ctx := activerecord.DryRun(context.Background(), os.Stdout)
+func DryRun(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, w)
+}
+
+// DryRunWriter returns the dry-run writer of the context, or nil when the
+// statements must be executed.
+func DryRunWriter(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(dryRunContextKey{}).(io.Writer)
+	return w
+}
+
+type schemaChangeContextKey struct{}
+
+// ContextWithSchemaChange returns a copy of the context for transactions, which
+// change the schema of the database. Adapters altering tables by re-creating
+// them disable foreign keys for such transactions and check them on commit.
+func ContextWithSchemaChange(ctx context.Context) context.Context {
+	return context.WithValue(ctx, schemaChangeContextKey{}, true)
+}
+
+// SchemaChangeFromContext returns true, when the context is used to change the
+// schema of the database.
+func SchemaChangeFromContext(ctx context.Context) bool {
+	change, _ := ctx.Value(schemaChangeContextKey{}).(bool)
+	return change
 }
 
 type ConnectionAdapter func(DatabaseConfig) (Conn, error)
 
+// connectionAdapters keeps registered connection adapters, adapters are
+// shared between connection handlers of all registries.
+type connectionAdapters struct {
+	adapters map[string]ConnectionAdapter
+	mu       sync.RWMutex
+}
+
+func newConnectionAdapters() *connectionAdapters {
+	return &connectionAdapters{adapters: make(map[string]ConnectionAdapter)}
+}
+
+func (a *connectionAdapters) register(adapter string, ca ConnectionAdapter) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, dup := a.adapters[adapter]; dup {
+		return fmt.Errorf("duplicate connection adapter")
+	}
+	a.adapters[adapter] = ca
+	return nil
+}
+
+func (a *connectionAdapters) lookup(adapter string) (ConnectionAdapter, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	ca, ok := a.adapters[adapter]
+	return ca, ok
+}
+
 // connectionHandler is responsible of keeping the state of established connections
 // adapters registration routine.
 type connectionHandler struct {
-	adapters map[string]ConnectionAdapter
+	adapters *connectionAdapters
 	conns    map[string]Conn
-	tx       map[uint64]Conn
+	replicas map[string]*replica
+	tx       map[string]Conn
 	mu       sync.RWMutex
 }
 
-func newConnectionHandler() *connectionHandler {
+func newConnectionHandler(adapters *connectionAdapters) *connectionHandler {
 	return &connectionHandler{
-		adapters: make(map[string]ConnectionAdapter),
+		adapters: adapters,
 		conns:    make(map[string]Conn),
-		tx:       make(map[uint64]Conn),
+		replicas: make(map[string]*replica),
+		tx:       make(map[string]Conn),
 	}
 }
 
 func (h *connectionHandler) RegisterConnectionAdapter(
 	adapter string, ca ConnectionAdapter,
 ) error {
-	if _, dup := h.adapters[adapter]; dup {
-		return fmt.Errorf("duplicate connection adapter")
-	}
-	h.adapters[adapter] = ca
-	return nil
+	return h.adapters.register(adapter, ca)
 }
 
 func (h *connectionHandler) ConnectionSpecificationName(name string) string {
 	return fmt.Sprintf("%s/%d", name, internal.GoroutineID())
 }
 
-func (h *connectionHandler) Transaction(ctx context.Context, fn func() error) error {
-	conn, err := h.RetrieveConnection(primaryConnectionName)
+func (h *connectionHandler) Transaction(
+	ctx context.Context, name string, fn func() error,
+) error {
+	spec := h.ConnectionSpecificationName(name)
+
+	h.mu.RLock()
+	_, nested := h.tx[spec]
+	h.mu.RUnlock()
+
+	// When the transaction is already open for this connection, the block
+	// becomes a part of the outer transaction.
+	if nested {
+		return fn()
+	}
+
+	conn, err := h.RetrieveConnection(name)
 	if err != nil {
 		return err
 	}
@@ -97,15 +319,14 @@ func (h *connectionHandler) Transaction(ctx context.Context, fn func() error) er
 	// operations for this connection will be finished with an error.
 	defer conn.Close()
 
-	goroutineID := internal.GoroutineID()
 	h.mu.Lock()
-	h.tx[goroutineID] = conn
+	h.tx[spec] = conn
 	h.mu.Unlock()
 
 	defer func() {
 		h.mu.Lock()
 		defer h.mu.Unlock()
-		delete(h.tx, goroutineID)
+		delete(h.tx, spec)
 	}()
 
 	if err = fn(); err != nil {
@@ -119,7 +340,7 @@ func (h *connectionHandler) Transaction(ctx context.Context, fn func() error) er
 }
 
 func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (Conn, error) {
-	newConnection, ok := h.adapters[c.Adapter]
+	newConnection, ok := h.adapters.lookup(c.Adapter)
 	if !ok {
 		return nil, &ErrAdapterNotFound{Adapter: c.Adapter}
 	}
@@ -136,6 +357,17 @@ func (h *connectionHandler) EstablishConnection(c DatabaseConfig) (Conn, error)
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if c.Role == RoleReading {
+		if _, dup := h.replicas[c.Name]; dup {
+			return nil, fmt.Errorf("replica %q already established", c.Name)
+		}
+		if c.Stickiness == 0 {
+			c.Stickiness = defaultStickiness
+		}
+		h.replicas[c.Name] = &replica{conn: conn, stickiness: c.Stickiness}
+		return conn, nil
+	}
+
 	if _, dup := h.conns[c.Name]; dup {
 		return nil, fmt.Errorf("connection %q already established", c.Name)
 	}
@@ -148,7 +380,7 @@ func (h *connectionHandler) RetrieveConnection(name string) (Conn, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	tx, ok := h.tx[internal.GoroutineID()]
+	tx, ok := h.tx[h.ConnectionSpecificationName(name)]
 	if ok {
 		return tx, nil
 	}
@@ -157,22 +389,73 @@ func (h *connectionHandler) RetrieveConnection(name string) (Conn, error) {
 	if !ok {
 		return nil, &ErrConnectionNotEstablished{Name: name}
 	}
+	if replica, ok := h.replicas[name]; ok {
+		return &replicatedConn{Conn: conn, replica: replica}, nil
+	}
 	return conn, nil
 }
 
+// Ping verifies that all established connections (including replicas) are
+// still alive.
+func (h *connectionHandler) Ping(ctx context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for name, conn := range h.conns {
+		if err := conn.Ping(ctx); err != nil {
+			return fmt.Errorf("connection %q: %w", name, err)
+		}
+	}
+	for name, replica := range h.replicas {
+		if err := replica.conn.Ping(ctx); err != nil {
+			return fmt.Errorf("replica %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// PoolStats returns statistics of connection pools of all established
+// connections. Replicas are reported with "/reading" suffix.
+func (h *connectionHandler) PoolStats() map[string]sql.DBStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make(map[string]sql.DBStats, len(h.conns)+len(h.replicas))
+	for name, conn := range h.conns {
+		stats[name] = conn.Stats()
+	}
+	for name, replica := range h.replicas {
+		stats[name+"/"+string(RoleReading)] = replica.conn.Stats()
+	}
+	return stats
+}
+
 func (h *connectionHandler) RemoveConnection(name string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	conn, ok := h.conns[name]
-	if !ok {
+	replica, hasReplica := h.replicas[name]
+	if !ok && !hasReplica {
 		return &ErrConnectionNotEstablished{Name: name}
 	}
 
 	delete(h.conns, name)
-	return conn.Close()
+	delete(h.replicas, name)
+
+	if hasReplica {
+		if err := replica.conn.Close(); err != nil {
+			return err
+		}
+	}
+	if ok {
+		return conn.Close()
+	}
+	return nil
 }
 
+// RegisterConnectionAdapter registers the database adapter, adapters are shared
+// between all registries.
 func RegisterConnectionAdapter(adapter string, ca ConnectionAdapter) {
 	err := globalConnectionHandler.RegisterConnectionAdapter(adapter, ca)
 	if err != nil {
@@ -193,20 +476,59 @@ func RegisterConnectionAdapter(adapter string, ca ConnectionAdapter) {
 //		Password: "pgpass",
 //		Database: "somedatabase",
 //	})
+//
+// Example for PostgreSQL read replica of the database above:
+//
+//	activerecord.EstablishConnection(activerecord.DatabaseConfig{
+//		Adapter:  "postgresql",
+//		Role:     activerecord.RoleReading,
+//		Host:     "replica.localhost",
+//		Username: "pguser",
+//		Password: "pgpass",
+//		Database: "somedatabase",
+//	})
 func EstablishConnection(c DatabaseConfig) (Conn, error) {
-	return globalConnectionHandler.EstablishConnection(c)
+	return defaultRegistry.EstablishConnection(c)
 }
 
 func RetrieveConnection(name string) (Conn, error) {
-	return globalConnectionHandler.RetrieveConnection(name)
+	return defaultRegistry.RetrieveConnection(name)
 }
 
 func RemoveConnection(name string) error {
-	return globalConnectionHandler.RemoveConnection(name)
+	return defaultRegistry.RemoveConnection(name)
+}
+
+// Ping verifies that all established connections are still alive, it could be
+// used to implement a health check of the application.
+//
+//	http.HandleFunc("/health", func(rw http.ResponseWriter, r *http.Request) {
+//		if err := activerecord.Ping(r.Context()); err != nil {
+//			rw.WriteHeader(http.StatusServiceUnavailable)
+//		}
+//	})
+func Ping(ctx context.Context) error {
+	return defaultRegistry.Ping(ctx)
+}
+
+// PoolStats returns statistics of connection pools by the connection name.
+func PoolStats() map[string]sql.DBStats {
+	return defaultRegistry.PoolStats()
 }
 
 // Transaction runs the given block in a database transaction, and returns the
-// result of the function.
+// result of the function. Use ContextWithLock to start the transaction, which
+// locks rows with Relation.Lock on all adapters.
 func Transaction(ctx context.Context, fn func() error) error {
-	return globalConnectionHandler.Transaction(ctx, fn)
+	return defaultRegistry.Transaction(ctx, fn)
+}
+
+// TransactionOn runs the given block in a transaction of the named database
+// connection, and returns the result of the function.
+//
+//	activerecord.TransactionOn(ctx, "analytics", func() error {
+//		return Report.Create(Hash{"name": "daily"}).Err()
+//	})
+func TransactionOn(ctx context.Context, name string, fn func() error) error {
+	return defaultRegistry.TransactionOn(ctx, name, fn)
 }
@@ -0,0 +1,205 @@
+package activerecord_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+func TestConnection_ConnectsTo(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	_, err = activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Name:     "analytics",
+		Adapter:  "sqlite3",
+		Database: t.Name() + "_analytics.db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer os.Remove(t.Name() + "_analytics.db")
+	defer activerecord.RemoveConnection("primary")
+	defer activerecord.RemoveConnection("analytics")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+		})
+	})
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.ConnectsTo("analytics")
+		m.CreateTable("reports", func(t *activerecord.Table) {
+			t.String("name")
+		})
+	})
+
+	Author := activerecord.New("author")
+	Report := activerecord.New("report", func(r *activerecord.R) {
+		r.ConnectsTo("analytics")
+	})
+	require.Equal(t, "analytics", Report.ConnectionName())
+
+	// The reports table exists only in the analytics database.
+	conn, err := activerecord.RetrieveConnection("primary")
+	require.NoError(t, err)
+	_, err = conn.ColumnDefinitions(context.TODO(), "reports")
+	require.True(t, errors.Is(err, activerecord.ErrTableNotExist{TableName: "reports"}))
+
+	err = activerecord.TransactionOn(context.TODO(), "analytics", func() error {
+		report := Report.Create(Hash{"name": "daily"})
+		if report.IsErr() {
+			return report.Err()
+		}
+		// Authors are stored in the primary database, which is not a part
+		// of the analytics transaction.
+		return Author.Create(Hash{"name": "Iain Banks"}).Err()
+	})
+	require.NoError(t, err)
+
+	err = activerecord.TransactionOn(context.TODO(), "analytics", func() error {
+		Report.Create(Hash{"name": "weekly"}).Expect("report was not created")
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	reports, err := Report.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, "daily", reports[0].Attribute("name"))
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+}
+
+func TestConnection_ReadingRole(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer os.Remove(t.Name() + "_replica.db")
+	defer activerecord.RemoveConnection("primary")
+
+	// Replication is out of scope of this test, therefore both databases
+	// are migrated separately.
+	_, err = activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Name:     "replica",
+		Adapter:  "sqlite3",
+		Database: t.Name() + "_replica.db",
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"primary", "replica"} {
+		activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+			m.ConnectsTo(name)
+			m.CreateTable("authors", func(t *activerecord.Table) {
+				t.String("name")
+			})
+		})
+	}
+	require.NoError(t, activerecord.RemoveConnection("replica"))
+
+	_, err = activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:    "sqlite3",
+		Role:       activerecord.RoleReading,
+		Database:   t.Name() + "_replica.db",
+		Stickiness: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	Author := activerecord.New("author")
+	Author.Create(Hash{"name": "Ursula Le Guin"}).Expect("author was not created")
+
+	// Right after the write, queries are routed to the writing database.
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+
+	// Explicit reading role ignores the stickiness of the connection.
+	readingCtx := activerecord.ConnectedTo(context.TODO(), activerecord.RoleReading)
+	authors, err = Author.WithContext(readingCtx).All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 0)
+
+	time.Sleep(50 * time.Millisecond)
+
+	authors, err = Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 0)
+
+	writingCtx := activerecord.ConnectedTo(context.TODO(), activerecord.RoleWriting)
+	authors, err = Author.WithContext(writingCtx).All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+}
+
+func TestConnection_Pool(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:      "sqlite3",
+		Database:     t.Name() + ".db",
+		MaxOpenConns: 4,
+		MaxIdleConns: 2,
+		BusyTimeout:  5 * time.Second,
+		JournalMode:  "WAL",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer os.Remove(t.Name() + ".db-shm")
+	defer os.Remove(t.Name() + ".db-wal")
+	defer activerecord.RemoveConnection("primary")
+
+	require.NoError(t, activerecord.Ping(context.TODO()))
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+		})
+	})
+
+	Author := activerecord.New("author")
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 40)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				errs <- Author.Create(Hash{"name": "Stanislaw Lem"}).Err()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 40)
+
+	stats := activerecord.PoolStats()
+	require.Contains(t, stats, "primary")
+	require.Equal(t, 4, stats["primary"].MaxOpenConnections)
+}
@@ -0,0 +1,329 @@
+package activerecord
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+// ErrEncryptionKey is returned when encryption keys are not configured or
+// the configured keys are invalid.
+type ErrEncryptionKey struct {
+	Description string
+}
+
+func (e *ErrEncryptionKey) Is(target error) bool {
+	_, ok := target.(*ErrEncryptionKey)
+	return ok
+}
+
+func (e *ErrEncryptionKey) Error() string {
+	return fmt.Sprintf("encryption key is invalid, %s", e.Description)
+}
+
+// ErrDecryption is returned when the encrypted value cannot be decrypted with
+// any of configured keys.
+type ErrDecryption struct {
+	Description string
+}
+
+func (e *ErrDecryption) Is(target error) bool {
+	_, ok := target.(*ErrDecryption)
+	return ok
+}
+
+func (e *ErrDecryption) Error() string {
+	return fmt.Sprintf("value cannot be decrypted, %s", e.Description)
+}
+
+// EncryptionConfig defines keys used to encrypt attributes.
+type EncryptionConfig struct {
+	// Keys are AES keys of 16, 24 or 32 bytes. The first key is used to encrypt
+	// values, the rest of keys are used only to decrypt values encrypted before
+	// the rotation of keys.
+	Keys [][]byte
+}
+
+var encryptionKeys = struct {
+	sync.RWMutex
+	keys [][]byte
+}{}
+
+// ConfigureEncryption sets keys of encrypted attributes.
+//
+//	err := activerecord.ConfigureEncryption(activerecord.EncryptionConfig{
+//		Keys: [][]byte{newKey, oldKey},
+//	})
+func ConfigureEncryption(config EncryptionConfig) error {
+	if len(config.Keys) == 0 {
+		return &ErrEncryptionKey{Description: "no keys given"}
+	}
+
+	keys := make([][]byte, 0, len(config.Keys))
+	for i, key := range config.Keys {
+		if _, err := aes.NewCipher(key); err != nil {
+			return &ErrEncryptionKey{Description: fmt.Sprintf("key %d: %s", i, err)}
+		}
+		keys = append(keys, append([]byte(nil), key...))
+	}
+
+	encryptionKeys.Lock()
+	defer encryptionKeys.Unlock()
+	encryptionKeys.keys = keys
+	return nil
+}
+
+// configuredKeys returns encryption keys, the first key is a primary one.
+func configuredKeys() ([][]byte, error) {
+	encryptionKeys.RLock()
+	defer encryptionKeys.RUnlock()
+
+	if len(encryptionKeys.keys) == 0 {
+		return nil, &ErrEncryptionKey{Description: "keys are not configured"}
+	}
+	return encryptionKeys.keys, nil
+}
+
+// EncryptionOption configures encrypted attributes.
+type EncryptionOption func(*Encrypted)
+
+// Deterministic makes the encryption deterministic, so the same values are
+// always encrypted into the same ciphertexts and could be queried.
+func Deterministic() EncryptionOption {
+	return func(e *Encrypted) { e.Deterministic = true }
+}
+
+// ciphertextPrefix marks values encrypted with the current version of the
+// encryption scheme.
+const ciphertextPrefix = "enc:v1:"
+
+// Encrypted is a string type, values of which are encrypted with AES-GCM using
+// configured keys and stored as base64 strings prefixed with "enc:v1:".
+//
+// By default each encryption uses a random nonce, so encrypted values cannot be
+// compared in queries. Deterministic encryption derives the nonce from the
+// value, values encrypted with the first key could be found with Where.
+//
+// Values without the prefix are plaintexts written before the encryption of
+// the column, they are read as is, so columns could be encrypted gradually
+// (see M.EncryptColumn).
+//
+// Ciphertexts are bound to the table and column. Reading of the ciphertext
+// copied from another column or encrypted with a removed key fails with
+// ErrDecryption.
+type Encrypted struct {
+	Deterministic bool
+
+	// additionalData is authenticated along with the ciphertext.
+	additionalData []byte
+}
+
+// bind returns a copy of the type, which ciphertexts are bound to the column
+// of the table.
+func (e *Encrypted) bind(tableName, columnName string) *Encrypted {
+	bound := *e
+	bound.additionalData = []byte(tableName + "." + columnName)
+	return &bound
+}
+
+func (*Encrypted) NativeType() string { return "VARCHAR" }
+
+func (*Encrypted) String() string { return "encrypted" }
+
+func (e *Encrypted) Deserialize(value interface{}) (interface{}, error) {
+	var strval string
+	switch value := value.(type) {
+	case string:
+		strval = value
+	case []byte:
+		strval = string(value)
+	default:
+		return nil, ErrType{TypeName: e.String(), Value: value}
+	}
+
+	if !strings.HasPrefix(strval, ciphertextPrefix) {
+		return strval, nil
+	}
+	return e.decrypt(strings.TrimPrefix(strval, ciphertextPrefix))
+}
+
+// Serialize encrypts the given value. The value is never decrypted before the
+// encryption, otherwise ciphertexts assigned to the attribute would be stored
+// as plaintexts.
+func (e *Encrypted) Serialize(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return e.encrypt(value)
+	case []byte:
+		return e.encrypt(string(value))
+	default:
+		return nil, ErrType{TypeName: e.String(), Value: value}
+	}
+}
+
+func (e *Encrypted) encrypt(plaintext string) (string, error) {
+	keys, err := configuredKeys()
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newGCM(keys[0])
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if e.Deterministic {
+		copy(nonce, deterministicNonce(keys[0], plaintext))
+	} else if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, []byte(plaintext), e.additionalData)
+	return ciphertextPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decrypt decrypts the base64 ciphertext with each configured key.
+func (e *Encrypted) decrypt(value string) (string, error) {
+	keys, err := configuredKeys()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", &ErrDecryption{Description: err.Error()}
+	}
+
+	for _, key := range keys {
+		aead, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(ciphertext) < aead.NonceSize() {
+			return "", &ErrDecryption{Description: "ciphertext is too short"}
+		}
+
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, sealed, e.additionalData); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", &ErrDecryption{Description: "message authentication failed"}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, &ErrEncryptionKey{Description: err.Error()}
+	}
+	return cipher.NewGCM(block)
+}
+
+// deterministicNonce returns the nonce derived from the plaintext with HMAC,
+// the HMAC key is derived from the encryption key, so the encryption key is
+// not used by both primitives.
+func deterministicNonce(key []byte, plaintext string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("activerecord deterministic nonce"))
+
+	mac = hmac.New(sha256.New, mac.Sum(nil))
+	mac.Write([]byte(plaintext))
+	return mac.Sum(nil)
+}
+
+// EncryptColumn encrypts values of the existing column with the first of the
+// configured keys, values encrypted with other keys are re-encrypted. On
+// rollback values of the column are decrypted.
+//
+//	activerecord.Migrate("003_encrypt_users_ssn", func(m *activerecord.M) {
+//		m.EncryptColumn("users", "ssn", activerecord.Deterministic())
+//	})
+func (m *M) EncryptColumn(tableName, columnName string, options ...EncryptionOption) {
+	encrypted := new(Encrypted)
+	for _, option := range options {
+		option(encrypted)
+	}
+	encrypted = encrypted.bind(tableName, columnName)
+
+	m.addOperation(migrationOperation{
+		name: "EncryptColumn",
+		apply: func(ctx context.Context, conn Conn) error {
+			return updateColumn(ctx, conn, tableName, columnName, encrypted)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return updateColumn(ctx, conn, tableName, columnName, new(String))
+		},
+	})
+}
+
+// updateColumn reads decrypted values of the column and writes them back
+// serialized with the given type.
+func updateColumn(ctx context.Context, conn Conn, tableName, columnName string, t Type) error {
+	columns, err := conn.ColumnDefinitions(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	var primaryKey *ColumnDefinition
+	for i := range columns {
+		if columns[i].IsPrimaryKey {
+			primaryKey = &columns[i]
+		}
+	}
+	if primaryKey == nil {
+		return &ErrUnknownPrimaryKey{Description: fmt.Sprintf("table %q has no primary key", tableName)}
+	}
+
+	op := QueryOperation{
+		Text:    fmt.Sprintf(`SELECT %q, %q FROM %q`, primaryKey.Name, columnName, tableName),
+		Columns: []string{primaryKey.Name, columnName},
+	}
+
+	// Read all rows before updating them, since not all adapters support
+	// writing, while reading cursor is open.
+	var rows []activesupport.Hash
+	err = conn.ExecQuery(ctx, &op, func(row activesupport.Hash) bool {
+		rows = append(rows, row)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	decrypter := new(Encrypted).bind(tableName, columnName)
+	for _, row := range rows {
+		if row[columnName] == nil {
+			continue
+		}
+		plaintext, err := decrypter.Deserialize(row[columnName])
+		if err != nil {
+			return err
+		}
+
+		err = conn.ExecUpdate(ctx, &UpdateOperation{
+			TableName:  tableName,
+			PrimaryKey: primaryKey.Name,
+			ColumnValues: []ColumnValue{
+				{Name: primaryKey.Name, Type: primaryKey.Type, Value: row[primaryKey.Name]},
+				{Name: columnName, Type: t, Value: plaintext},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
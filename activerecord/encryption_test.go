@@ -0,0 +1,125 @@
+package activerecord_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+func TestEncrypts(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	var (
+		oldKey = bytes.Repeat([]byte{1}, 32)
+		newKey = bytes.Repeat([]byte{2}, 32)
+	)
+
+	err = activerecord.ConfigureEncryption(activerecord.EncryptionConfig{
+		Keys: [][]byte{[]byte("short")},
+	})
+	require.Error(t, err)
+
+	err = activerecord.ConfigureEncryption(activerecord.EncryptionConfig{
+		Keys: [][]byte{oldKey},
+	})
+	require.NoError(t, err)
+
+	activerecord.Migrate(t.Name()+"_create", func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("name")
+			t.String("ssn")
+			t.String("token")
+		})
+	})
+
+	// Rows existing before the encryption of the columns are stored as is.
+	PlainUser := activerecord.New("user")
+	plain := PlainUser.Create(Hash{"name": "Jules", "ssn": "123-45-6789"}).Unwrap()
+
+	activerecord.Migrate(t.Name()+"_encrypt", func(m *activerecord.M) {
+		m.EncryptColumn("users", "ssn", activerecord.Deterministic())
+	})
+
+	User := activerecord.New("user", func(r *activerecord.R) {
+		r.Encrypts("ssn", activerecord.Deterministic())
+		r.Encrypts("token")
+	})
+
+	ssn := PlainUser.Find(plain.ID()).Unwrap().Attribute("ssn")
+	require.NotEqual(t, "123-45-6789", ssn)
+	require.Equal(t, "123-45-6789", User.Find(plain.ID()).Unwrap().Attribute("ssn"))
+
+	user := User.Create(Hash{"name": "Victor", "ssn": "987-65-4321", "token": "secret"}).Unwrap()
+	require.Equal(t, "secret", user.Attribute("token"))
+
+	raw := PlainUser.Find(user.ID()).Unwrap()
+	require.NotEqual(t, "secret", raw.Attribute("token"))
+	require.Equal(t, "secret", User.Find(user.ID()).Unwrap().Attribute("token"))
+
+	// Ciphertexts are marked with the version of the encryption.
+	rawSSN := raw.Attribute("ssn").(string)
+	rawToken := raw.Attribute("token").(string)
+	require.True(t, strings.HasPrefix(rawSSN, "enc:v1:"), rawSSN)
+	require.True(t, strings.HasPrefix(rawToken, "enc:v1:"), rawToken)
+
+	// Ciphertexts assigned to attributes are encrypted again, so they are
+	// read back as is, ciphertexts of other columns are not valid values.
+	copied := User.Create(Hash{"name": "Mallory", "ssn": rawSSN}).Unwrap()
+	require.Equal(t, rawSSN, User.Find(copied.ID()).Unwrap().Attribute("ssn"))
+	require.Error(t, User.Create(Hash{"name": "Mallory", "token": rawSSN}).Err())
+
+	// Ciphertexts copied from other columns are not decrypted.
+	copied = PlainUser.Create(Hash{"name": "Mallory", "ssn": rawToken}).Unwrap()
+	err = User.Find(copied.ID()).Err()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, new(activerecord.ErrDecryption)), err)
+
+	_, err = copied.HardDelete()
+	require.NoError(t, err)
+
+	// Deterministic encryption allows to query encrypted values.
+	users, err := User.Where("ssn", "987-65-4321").ToA()
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "Victor", users[0].Attribute("name"))
+
+	// Values encrypted with previous keys are decrypted after the rotation.
+	err = activerecord.ConfigureEncryption(activerecord.EncryptionConfig{
+		Keys: [][]byte{newKey, oldKey},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "123-45-6789", User.Find(plain.ID()).Unwrap().Attribute("ssn"))
+
+	activerecord.Migrate(t.Name()+"_reencrypt", func(m *activerecord.M) {
+		m.EncryptColumn("users", "ssn", activerecord.Deterministic())
+	})
+	require.NotEqual(t, ssn, PlainUser.Find(plain.ID()).Unwrap().Attribute("ssn"))
+
+	err = activerecord.ConfigureEncryption(activerecord.EncryptionConfig{
+		Keys: [][]byte{newKey},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "123-45-6789", User.Find(plain.ID()).Unwrap().Attribute("ssn"))
+
+	// The token is still encrypted with the removed key.
+	err = User.Find(user.ID()).Err()
+	require.True(t, errors.Is(err, new(activerecord.ErrDecryption)), err)
+
+	users, err = User.Where("ssn", "123-45-6789").ToA()
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,29 +18,34 @@ const (
 type Table struct {
 	name        string
 	primaryKey  string
-	foreignKeys []string
+	foreignKeys []ForeignKeyDefinition
+	indexes     []IndexDefinition
 
-	columns map[string]Type
+	columns []ColumnDefinition
 }
 
 func (tb *Table) Name() string {
 	return tb.name
 }
 
-func (tb *Table) ForeignKeys() []string {
+func (tb *Table) ForeignKeys() []ForeignKeyDefinition {
 	return tb.foreignKeys
 }
 
+func (tb *Table) Indexes() []IndexDefinition {
+	return tb.indexes
+}
+
 func (tb *Table) Columns() (columns []ColumnDefinition) {
-	for columnName, columnType := range tb.columns {
-		columns = append(columns, ColumnDefinition{
-			Name:         columnName,
-			Type:         columnType,
-			IsPrimaryKey: columnName == tb.primaryKey,
-		})
+	var hasPrimaryKey bool
+
+	for _, column := range tb.columns {
+		column.IsPrimaryKey = column.Name == tb.primaryKey
+		hasPrimaryKey = hasPrimaryKey || column.IsPrimaryKey
+		columns = append(columns, column)
 	}
 
-	if _, ok := tb.columns[tb.primaryKey]; !ok {
+	if !hasPrimaryKey {
 		columns = append(columns, ColumnDefinition{
 			Name:         "id",
 			Type:         new(Int64),
@@ -54,24 +60,311 @@ func (tb *Table) PrimaryKey(primaryKey string) {
 	tb.primaryKey = primaryKey
 }
 
-func (tb *Table) DefineColumn(name string, t Type) {
-	tb.columns[name] = t
+// ColumnOption configures the column definition.
+type ColumnOption func(*ColumnDefinition)
+
+// NotNull forbids NULL values of the column.
+func NotNull() ColumnOption {
+	return func(column *ColumnDefinition) { column.NotNull = true }
+}
+
+// Default sets a literal default value of the column.
+//
+//	t.String("status", activerecord.NotNull(), activerecord.Default("draft"))
+func Default(value interface{}) ColumnOption {
+	return func(column *ColumnDefinition) { column.Default = value }
+}
+
+// DefaultSQL sets a default value of the column evaluated by the database.
+//
+//	t.DateTime("created_at", activerecord.DefaultSQL("CURRENT_TIMESTAMP"))
+func DefaultSQL(expr string) ColumnOption {
+	return func(column *ColumnDefinition) { column.DefaultSQL = expr }
 }
 
-func (tb *Table) Int64(name string) {
-	tb.DefineColumn(name, new(Int64))
+// Limit sets the maximum length of the string column.
+func Limit(limit int) ColumnOption {
+	return func(column *ColumnDefinition) { column.Limit = limit }
 }
 
-func (tb *Table) String(name string) {
-	tb.DefineColumn(name, new(String))
+// Precision sets the total number of digits of the numeric column.
+func Precision(precision int) ColumnOption {
+	return func(column *ColumnDefinition) { column.Precision = precision }
 }
 
-func (tb *Table) DateTime(name string) {
-	tb.DefineColumn(name, new(DateTime))
+// Scale sets the number of digits after the decimal point of the numeric column.
+func Scale(scale int) ColumnOption {
+	return func(column *ColumnDefinition) { column.Scale = scale }
 }
 
-func (tb *Table) ForeignKey(target string) {
-	tb.foreignKeys = append(tb.foreignKeys, target)
+// Check adds a check constraint to the column.
+//
+//	t.Int64("year", activerecord.Check("year > 0"))
+func Check(cond string) ColumnOption {
+	return func(column *ColumnDefinition) { column.Check = cond }
+}
+
+func newColumnDefinition(name string, t Type, options []ColumnOption) ColumnDefinition {
+	column := ColumnDefinition{Name: name, Type: t}
+	for _, option := range options {
+		option(&column)
+	}
+	return column
+}
+
+func (tb *Table) DefineColumn(name string, t Type, options ...ColumnOption) {
+	column := newColumnDefinition(name, t, options)
+
+	for i := range tb.columns {
+		if tb.columns[i].Name == name {
+			tb.columns[i] = column
+			return
+		}
+	}
+	tb.columns = append(tb.columns, column)
+}
+
+func (tb *Table) Int64(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(Int64), options...)
+}
+
+func (tb *Table) Float64(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(Float64), options...)
+}
+
+func (tb *Table) String(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(String), options...)
+}
+
+func (tb *Table) DateTime(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(DateTime), options...)
+}
+
+// Decimal adds a column of arbitrary-precision decimal numbers.
+//
+//	t.Decimal("price", activerecord.Precision(10), activerecord.Scale(2))
+func (tb *Table) Decimal(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(Decimal), options...)
+}
+
+// LockVersion adds a column of the record version used for optimistic locking.
+//
+//	t.LockVersion()
+func (tb *Table) LockVersion(options ...ColumnOption) {
+	options = append([]ColumnOption{NotNull(), Default(int64(0))}, options...)
+	tb.DefineColumn(lockingColumn, new(Int64), options...)
+}
+
+// UUID adds a column of universally unique identifiers.
+//
+//	t.UUID("id")
+//	t.PrimaryKey("id")
+func (tb *Table) UUID(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(UUID), options...)
+}
+
+// Binary adds a column of binary data.
+func (tb *Table) Binary(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(Binary), options...)
+}
+
+// JSON adds a column of JSON objects.
+func (tb *Table) JSON(name string, options ...ColumnOption) {
+	tb.DefineColumn(name, new(JSON), options...)
+}
+
+// Enum adds a string column restricted to the list of values with a check
+// constraint.
+//
+//	t.Enum("status", []string{"draft", "published"}, activerecord.Default("draft"))
+func (tb *Table) Enum(name string, values []string, options ...ColumnOption) {
+	check := Check(enumCheck(name, values))
+	tb.DefineColumn(name, &Enum{Values: values}, append(options, check)...)
+}
+
+// enumCheck returns the check constraint restricting the column to the list
+// of values.
+func enumCheck(name string, values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+strings.ReplaceAll(value, "'", "''")+"'")
+	}
+	return fmt.Sprintf("%q IN (%s)", name, strings.Join(quoted, ", "))
+}
+
+// enumValues returns values of the enum column restricted by the check
+// constraint of Table.Enum, ok is false for other constraints.
+func enumValues(name, check string) (values []string, ok bool) {
+	list := strings.TrimPrefix(check, fmt.Sprintf("%q IN (", name))
+	if list == check || !strings.HasSuffix(list, ")") {
+		return nil, false
+	}
+	list = strings.TrimSuffix(list, ")")
+
+	for len(list) != 0 {
+		if list[0] != '\'' {
+			return nil, false
+		}
+
+		// Quotes within values are escaped with another quote.
+		var value strings.Builder
+		i := 1
+		for ; i < len(list); i++ {
+			if list[i] == '\'' {
+				if i+1 < len(list) && list[i+1] == '\'' {
+					i++
+				} else {
+					break
+				}
+			}
+			value.WriteByte(list[i])
+		}
+		if i >= len(list) {
+			return nil, false
+		}
+
+		values = append(values, value.String())
+		list = strings.TrimPrefix(list[i+1:], ", ")
+	}
+
+	// Ensure the constraint is exactly the one created for values.
+	return values, enumCheck(name, values) == check
+}
+
+// ReferentialAction is an action performed on the referencing rows, when the
+// referenced row is deleted or its primary key is updated.
+type ReferentialAction string
+
+const (
+	// Cascade deletes or updates referencing rows along with the referenced row.
+	Cascade ReferentialAction = "CASCADE"
+
+	// Nullify sets foreign keys of referencing rows to NULL.
+	Nullify ReferentialAction = "SET NULL"
+
+	// Restrict prevents deletion or update of the referenced row.
+	Restrict ReferentialAction = "RESTRICT"
+
+	// NoAction is the default action, the constraint is checked at the end
+	// of the statement.
+	NoAction ReferentialAction = "NO ACTION"
+)
+
+// ForeignKeyOption configures the foreign key definition.
+type ForeignKeyOption func(*ForeignKeyDefinition)
+
+// ReferenceColumn sets the name of the referencing column, by default the name
+// is derived from the referenced table, e.g. "author_id" for "authors".
+func ReferenceColumn(name string) ForeignKeyOption {
+	return func(fk *ForeignKeyDefinition) { fk.Column = name }
+}
+
+// ReferencePrimaryKey sets the referenced column, "id" by default.
+func ReferencePrimaryKey(name string) ForeignKeyOption {
+	return func(fk *ForeignKeyDefinition) { fk.PrimaryKey = name }
+}
+
+// ConstraintName sets the name of the foreign key constraint, by default the
+// name is derived from the table and column names, e.g. "fk_books_on_author_id".
+func ConstraintName(name string) ForeignKeyOption {
+	return func(fk *ForeignKeyDefinition) { fk.Name = name }
+}
+
+// OnDelete sets the action performed on deletion of the referenced row.
+//
+//	t.ForeignKey("authors", activerecord.OnDelete(activerecord.Cascade))
+func OnDelete(action ReferentialAction) ForeignKeyOption {
+	return func(fk *ForeignKeyDefinition) { fk.OnDelete = action }
+}
+
+// OnUpdate sets the action performed on update of the referenced primary key.
+func OnUpdate(action ReferentialAction) ForeignKeyOption {
+	return func(fk *ForeignKeyDefinition) { fk.OnUpdate = action }
+}
+
+func newForeignKeyDefinition(
+	tableName, target string, options []ForeignKeyOption,
+) ForeignKeyDefinition {
+	fk := ForeignKeyDefinition{
+		TableName:       tableName,
+		Column:          fmt.Sprintf("%s_id", strings.TrimSuffix(target, "s")),
+		ReferencedTable: target,
+		PrimaryKey:      defaultPrimaryKeyName,
+	}
+	for _, option := range options {
+		option(&fk)
+	}
+	if fk.Name == "" {
+		fk.Name = fmt.Sprintf("fk_%s_on_%s", tableName, fk.Column)
+	}
+	return fk
+}
+
+// ForeignKey adds a foreign key constraint referencing the target table.
+//
+//	m.CreateTable("books", func(t *activerecord.Table) {
+//		t.Int64("writer_id")
+//		t.ForeignKey("authors",
+//			activerecord.ReferenceColumn("writer_id"),
+//			activerecord.OnDelete(activerecord.Nullify),
+//		)
+//	})
+func (tb *Table) ForeignKey(target string, options ...ForeignKeyOption) {
+	tb.foreignKeys = append(tb.foreignKeys, newForeignKeyDefinition(tb.name, target, options))
+}
+
+// IndexOption configures the index definition.
+type IndexOption func(*IndexDefinition)
+
+// Unique makes the index unique.
+func Unique() IndexOption {
+	return func(index *IndexDefinition) { index.Unique = true }
+}
+
+// IndexName sets the name of the index, by default the name is derived from
+// the table name and the names of indexed columns, e.g. "index_books_on_title".
+func IndexName(name string) IndexOption {
+	return func(index *IndexDefinition) { index.Name = name }
+}
+
+// Columns adds columns to the composite index.
+//
+//	t.Index("author_id", activerecord.Columns("title"), activerecord.Unique())
+func Columns(columnNames ...string) IndexOption {
+	return func(index *IndexDefinition) {
+		index.Columns = append(index.Columns, columnNames...)
+	}
+}
+
+// Partial makes the index partial, only rows matching the condition are
+// included into the index.
+//
+//	t.Index("email", activerecord.Unique(), activerecord.Partial("deleted_at IS NULL"))
+func Partial(cond string) IndexOption {
+	return func(index *IndexDefinition) { index.Where = cond }
+}
+
+func newIndexDefinition(tableName, columnName string, options []IndexOption) IndexDefinition {
+	index := IndexDefinition{TableName: tableName, Columns: []string{columnName}}
+	for _, option := range options {
+		option(&index)
+	}
+	if index.Name == "" {
+		index.Name = fmt.Sprintf(
+			"index_%s_on_%s", tableName, strings.Join(index.Columns, "_and_"),
+		)
+	}
+	return index
+}
+
+// Index adds an index of the column to the table.
+//
+//	m.CreateTable("users", func(t *activerecord.Table) {
+//		t.String("email")
+//		t.Index("email", activerecord.Unique())
+//	})
+func (tb *Table) Index(columnName string, options ...IndexOption) {
+	tb.indexes = append(tb.indexes, newIndexDefinition(tb.name, columnName, options))
 }
 
 type References struct {
@@ -93,17 +386,160 @@ func (tb *Table) References(target string, init ...References) {
 	tb.DefineColumn(ref, new(Int64))
 }
 
+// ErrIrreversibleMigration is returned on attempt to roll back the migration,
+// which cannot be reverted automatically.
+type ErrIrreversibleMigration struct {
+	Version   string
+	Operation string
+}
+
+func (e *ErrIrreversibleMigration) Is(target error) bool {
+	_, ok := target.(*ErrIrreversibleMigration)
+	return ok
+}
+
+func (e *ErrIrreversibleMigration) Error() string {
+	return fmt.Sprintf("migration %q is irreversible: %s cannot be reverted", e.Version, e.Operation)
+}
+
+type migrationFunc func(ctx context.Context, conn Conn) error
+
+// migrationOperation is a single schema change of the migration. Operation
+// without revert function is irreversible.
+type migrationOperation struct {
+	name   string
+	table  *Table
+	apply  migrationFunc
+	revert migrationFunc
+}
+
+// M is a migration definition. Operations defined within the migration are
+// reversible when possible, so the migration could be rolled back without
+// defining Down block explicitly.
+//
+//	activerecord.Migrate("001_create_authors", func(m *activerecord.M) {
+//		m.CreateTable("authors", func(t *activerecord.Table) {
+//			t.String("name")
+//		})
+//	})
 type M struct {
-	tables     map[string]Table
-	references map[string]string
+	id string
+
+	change []migrationOperation
+	up     []migrationOperation
+	down   []migrationOperation
+
+	// operations is a list of operations, where newly defined operations
+	// are recorded: change (default), up or down.
+	operations *[]migrationOperation
+
+	connections    *connectionHandler
+	connectionName string
+}
+
+func newMigration(id string, connections *connectionHandler) *M {
+	m := M{
+		id:             id,
+		connections:    connections,
+		connectionName: primaryConnectionName,
+	}
+	m.operations = &m.change
+	return &m
+}
+
+// ID returns the version of the migration.
+func (m *M) ID() string {
+	return m.id
+}
+
+// ConnectsTo sets the name of the database connection the migration is applied
+// to. By default migrations are applied to the "primary" connection.
+//
+//	activerecord.Migrate("001_create_reports", func(m *activerecord.M) {
+//		m.ConnectsTo("analytics")
+//		m.CreateTable("reports", func(t *activerecord.Table) {
+//			t.String("name")
+//		})
+//	})
+func (m *M) ConnectsTo(name string) {
+	m.connectionName = name
+}
+
+// Up defines operations applied by the migration, use it along with Down, when
+// operations of the migration cannot be reverted automatically.
+//
+//	activerecord.Migrate("002_drop_reports", func(m *activerecord.M) {
+//		m.Up(func(m *activerecord.M) {
+//			m.DropTable("reports")
+//		})
+//		m.Down(func(m *activerecord.M) {
+//			m.CreateTable("reports", func(t *activerecord.Table) {
+//				t.String("name")
+//			})
+//		})
+//	})
+func (m *M) Up(init func(m *M)) {
+	m.record(&m.up, init)
+}
+
+// Down defines operations applied on the migration rollback.
+func (m *M) Down(init func(m *M)) {
+	m.record(&m.down, init)
+}
+
+func (m *M) record(operations *[]migrationOperation, init func(m *M)) {
+	prev := m.operations
+	defer func() { m.operations = prev }()
+
+	m.operations = operations
+	init(m)
+}
+
+func (m *M) addOperation(op migrationOperation) {
+	*m.operations = append(*m.operations, op)
+}
+
+// applyOperations returns a list of operations applied by the migration.
+func (m *M) applyOperations() []migrationFunc {
+	ops := make([]migrationFunc, 0, len(m.change)+len(m.up))
+	for _, op := range m.change {
+		ops = append(ops, op.apply)
+	}
+	for _, op := range m.up {
+		ops = append(ops, op.apply)
+	}
+	return ops
+}
+
+// revertOperations returns a list of operations applied on the migration
+// rollback. When the Down block is not defined, operations are reverted in
+// the reverse order.
+func (m *M) revertOperations() ([]migrationFunc, error) {
+	if len(m.down) != 0 {
+		ops := make([]migrationFunc, 0, len(m.down))
+		for _, op := range m.down {
+			ops = append(ops, op.apply)
+		}
+		return ops, nil
+	}
+	if len(m.up) != 0 {
+		return nil, &ErrIrreversibleMigration{Version: m.id, Operation: "up"}
+	}
 
-	connections *connectionHandler
+	ops := make([]migrationFunc, 0, len(m.change))
+	for i := len(m.change) - 1; i >= 0; i-- {
+		if m.change[i].revert == nil {
+			return nil, &ErrIrreversibleMigration{Version: m.id, Operation: m.change[i].name}
+		}
+		ops = append(ops, m.change[i].revert)
+	}
+	return ops, nil
 }
 
 func (m *M) TableExists(tableName string) Result[bool] {
 	return FutureOk(true).AndThen(
 		func(bool) Result[bool] {
-			conn, err := m.connections.RetrieveConnection(primaryConnectionName)
+			conn, err := m.connections.RetrieveConnection(m.connectionName)
 			if err != nil {
 				return Err[bool](err)
 			}
@@ -120,98 +556,456 @@ func (m *M) TableExists(tableName string) Result[bool] {
 	)
 }
 
+// CreateTable creates a new table, the table is dropped on rollback.
 func (m *M) CreateTable(name string, init func(*Table)) {
-	table := Table{
-		name:    name,
-		columns: make(map[string]Type),
+	table := &Table{
+		name: name,
+	}
+	init(table)
+
+	m.addOperation(migrationOperation{
+		name:  "CreateTable",
+		table: table,
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.CreateTable(ctx, table)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.DropTable(ctx, name)
+		},
+	})
+}
+
+// DropTable drops the table. The operation is reversible only when the table
+// definition is given.
+//
+//	m.DropTable("reports", func(t *activerecord.Table) {
+//		t.String("name")
+//	})
+func (m *M) DropTable(name string, init ...func(*Table)) {
+	op := migrationOperation{
+		name: "DropTable",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.DropTable(ctx, name)
+		},
+	}
+
+	switch len(init) {
+	case 0:
+	case 1:
+		table := &Table{name: name}
+		init[0](table)
+
+		op.revert = func(ctx context.Context, conn Conn) error {
+			return conn.CreateTable(ctx, table)
+		}
+	default:
+		panic(&ErrMultipleVariadicArguments{Name: "init"})
 	}
-	init(&table)
+	m.addOperation(op)
+}
+
+// AddColumn adds a new column to the table, the column is removed on rollback.
+//
+//	m.AddColumn("authors", "email", new(activerecord.String), activerecord.Limit(255))
+func (m *M) AddColumn(tableName, columnName string, columnType Type, options ...ColumnOption) {
+	column := newColumnDefinition(columnName, columnType, options)
 
-	m.tables[name] = table
+	m.addOperation(migrationOperation{
+		name: "AddColumn",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.AddColumn(ctx, tableName, column)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveColumn(ctx, tableName, columnName)
+		},
+	})
 }
 
-func (m *M) AddForeignKey(owner, target string) {
-	if table, ok := m.tables[owner]; ok {
-		// If it's a new table, add a foreign key directly into the table definition.
-		table.ForeignKey(target)
-		m.tables[owner] = table
-	} else {
-		m.references[owner] = target
+// RemoveColumn removes the column from the table. The operation is reversible
+// only when the type of the column is given.
+//
+//	m.RemoveColumn("authors", "email", new(activerecord.String))
+func (m *M) RemoveColumn(tableName, columnName string, columnType ...Type) {
+	op := migrationOperation{
+		name: "RemoveColumn",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveColumn(ctx, tableName, columnName)
+		},
 	}
+
+	switch len(columnType) {
+	case 0:
+	case 1:
+		column := ColumnDefinition{Name: columnName, Type: columnType[0]}
+		op.revert = func(ctx context.Context, conn Conn) error {
+			return conn.AddColumn(ctx, tableName, column)
+		}
+	default:
+		panic(&ErrMultipleVariadicArguments{Name: "columnType"})
+	}
+	m.addOperation(op)
 }
 
-func Migrate(id string, init func(m *M)) {
-	m := M{
-		tables:      make(map[string]Table),
-		references:  make(map[string]string),
-		connections: globalConnectionHandler,
-	}
-
-	init(&m)
-
-	// Before applying further migrations, ensure that table exists.
-	// This operation should be unwrapped within a migration transaction.
-	schema := m.TableExists(SchemaMigrationsName).AndThen(
-		func(exists bool) Result[bool] {
-			if !exists {
-				m.CreateTable(SchemaMigrationsName, func(t *Table) {
-					t.PrimaryKey("version")
-					t.String("version")
-					t.DateTime("created_at")
-				})
-			}
-			return Ok(true)
+// RenameColumn renames the column of the table, the column gets the original
+// name back on rollback.
+func (m *M) RenameColumn(tableName, columnName, newColumnName string) {
+	m.addOperation(migrationOperation{
+		name: "RenameColumn",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.RenameColumn(ctx, tableName, columnName, newColumnName)
 		},
-	)
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.RenameColumn(ctx, tableName, newColumnName, columnName)
+		},
+	})
+}
+
+// ChangeColumn changes the type and options of the column, the operation is
+// irreversible, use Up and Down to define the rollback explicitly.
+func (m *M) ChangeColumn(
+	tableName, columnName string, columnType Type, options ...ColumnOption,
+) {
+	column := newColumnDefinition(columnName, columnType, options)
+
+	m.addOperation(migrationOperation{
+		name: "ChangeColumn",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.ChangeColumn(ctx, tableName, column)
+		},
+	})
+}
+
+// Execute executes an arbitrary SQL statement, the operation is irreversible.
+//
+//	m.Execute(`UPDATE "books" SET "year" = 1965 WHERE "title" = 'Dune'`)
+func (m *M) Execute(stmt string) {
+	m.addOperation(migrationOperation{
+		name: "Execute",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.Execute(ctx, stmt)
+		},
+	})
+}
 
-	// TODO: Use the specified connection name, instead of the default.
-	err := m.connections.Transaction(context.TODO(), func() error {
-		if schema.IsErr() {
-			return schema.Err()
+// AddForeignKey adds a foreign key to the owner table, the foreign key is
+// removed on rollback. When the owner table is created within the same
+// migration, the foreign key is added into the table definition.
+//
+//	m.AddForeignKey("books", "authors", activerecord.OnDelete(activerecord.Cascade))
+func (m *M) AddForeignKey(owner, target string, options ...ForeignKeyOption) {
+	for _, op := range *m.operations {
+		if op.table != nil && op.table.Name() == owner {
+			// If it's a new table, add a foreign key directly into the table definition.
+			op.table.ForeignKey(target, options...)
+			return
 		}
+	}
 
-		conn, err := m.connections.RetrieveConnection(primaryConnectionName)
-		if err != nil {
+	fk := newForeignKeyDefinition(owner, target, options)
+
+	m.addOperation(migrationOperation{
+		name: "AddForeignKey",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.AddForeignKey(ctx, fk)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveForeignKey(ctx, fk)
+		},
+	})
+}
+
+// RemoveForeignKey removes the foreign key from the owner table. The foreign
+// key is identified in the same way as for AddForeignKey, so the same options
+// must be given to revert the operation properly.
+func (m *M) RemoveForeignKey(owner, target string, options ...ForeignKeyOption) {
+	fk := newForeignKeyDefinition(owner, target, options)
+
+	m.addOperation(migrationOperation{
+		name: "RemoveForeignKey",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveForeignKey(ctx, fk)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.AddForeignKey(ctx, fk)
+		},
+	})
+}
+
+// AddIndex adds an index to the table, the index is removed on rollback.
+//
+//	m.AddIndex("books", "author_id")
+//	m.AddIndex("books", "author_id", activerecord.Columns("title"), activerecord.Unique())
+func (m *M) AddIndex(tableName, columnName string, options ...IndexOption) {
+	index := newIndexDefinition(tableName, columnName, options)
+
+	m.addOperation(migrationOperation{
+		name: "AddIndex",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.AddIndex(ctx, index)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveIndex(ctx, tableName, index.Name)
+		},
+	})
+}
+
+// RemoveIndex removes the index from the table. The index is identified by the
+// name, derived in the same way as for AddIndex, so the same options must be
+// given to revert the operation properly.
+func (m *M) RemoveIndex(tableName, columnName string, options ...IndexOption) {
+	index := newIndexDefinition(tableName, columnName, options)
+
+	m.addOperation(migrationOperation{
+		name: "RemoveIndex",
+		apply: func(ctx context.Context, conn Conn) error {
+			return conn.RemoveIndex(ctx, tableName, index.Name)
+		},
+		revert: func(ctx context.Context, conn Conn) error {
+			return conn.AddIndex(ctx, index)
+		},
+	})
+}
+
+func Migrate(id string, init func(m *M)) {
+	defaultRegistry.Migrate(id, init)
+}
+
+// Register defines the migration within the default registry without applying
+// it. Registered migrations are applied by the Migrator.
+//
+//	activerecord.Register("001_create_authors", func(m *activerecord.M) {
+//		m.CreateTable("authors", func(t *activerecord.Table) {
+//			t.String("name")
+//		})
+//	})
+//
+//	err := activerecord.NewMigrator().Migrate(ctx)
+func Register(id string, init func(m *M)) {
+	defaultRegistry.Register(id, init)
+}
+
+// Rollback reverts the last applied migrations defined within the default
+// registry, and removes their versions from the schema migrations table.
+func Rollback(steps int) error {
+	return defaultRegistry.Rollback(steps)
+}
+
+// Redo rolls back the last applied migrations and applies them again.
+func Redo(steps int) error {
+	return defaultRegistry.Redo(steps)
+}
+
+// Migrate applies the migration to the database connection of the registry.
+// The migration is kept by the registry, so it could be rolled back later.
+// Method panics on failure, use Register along with Migrator to handle errors.
+func (reg *Registry) Migrate(id string, init func(m *M)) {
+	m := newMigration(id, reg.connections)
+	init(m)
+
+	reg.addMigration(m)
+
+	if err := reg.migrate(context.TODO(), m); err != nil {
+		panic(err)
+	}
+}
+
+// Register defines the migration within the registry without applying it.
+func (reg *Registry) Register(id string, init func(m *M)) {
+	m := newMigration(id, reg.connections)
+	init(m)
+	reg.addMigration(m)
+}
+
+// Rollback reverts the last applied migrations defined within the registry.
+func (reg *Registry) Rollback(steps int) error {
+	_, err := reg.rollback(context.TODO(), steps)
+	return err
+}
+
+// Redo rolls back the last applied migrations defined within the registry and
+// applies them again.
+func (reg *Registry) Redo(steps int) error {
+	ctx := context.TODO()
+
+	reverted, err := reg.rollback(ctx, steps)
+	if err != nil {
+		return err
+	}
+	for i := len(reverted) - 1; i >= 0; i-- {
+		if err = reg.migrate(ctx, reverted[i]); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		schemaTable, ok := m.tables[SchemaMigrationsName]
-		if ok {
-			delete(m.tables, SchemaMigrationsName)
+func (reg *Registry) addMigration(m *M) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
 
-			err := conn.CreateTable(context.TODO(), &schemaTable)
-			if err != nil {
-				return err
-			}
+	for i := range reg.migrations {
+		if reg.migrations[i].id == m.id {
+			reg.migrations[i] = m
+			return
 		}
+	}
+	reg.migrations = append(reg.migrations, m)
+}
 
-		SchemaMigration := New("schema_migration")
-		migration := SchemaMigration.Create(Hash{"version": id, "created_at": time.Now()})
+// sortedMigrations returns registered migrations ordered by versions.
+func (reg *Registry) sortedMigrations() []*M {
+	reg.mu.Lock()
+	migrations := make([]*M, len(reg.migrations))
+	copy(migrations, reg.migrations)
+	reg.mu.Unlock()
 
-		if errors.Is(migration.Err(), new(ErrRecordNotUnique)) {
-			// Commit the transaction since it's already applied.
-			return nil
-		} else if migration.IsErr() {
-			return migration.Err()
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].id < migrations[j].id
+	})
+	return migrations
+}
+
+// createSchemaMigrations creates a table of applied migration versions.
+func createSchemaMigrations(ctx context.Context, conn Conn) error {
+	table := Table{name: SchemaMigrationsName}
+	table.PrimaryKey("version")
+	table.String("version")
+	table.DateTime("created_at")
+	return conn.CreateTable(ctx, &table)
+}
+
+// appliedVersions returns versions stored in the schema migrations table. The
+// returned map is nil, when the table does not exist.
+func appliedVersions(ctx context.Context, conn Conn) (map[string]bool, error) {
+	// Replicas might be behind the writing database, read the actual state.
+	ctx = ConnectedTo(ctx, RoleWriting)
+
+	_, err := conn.ColumnDefinitions(ctx, SchemaMigrationsName)
+	if errors.Is(err, ErrTableNotExist{TableName: SchemaMigrationsName}) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	op := QueryOperation{
+		Text:    fmt.Sprintf(`SELECT "version" FROM %q`, SchemaMigrationsName),
+		Columns: []string{"version"},
+	}
+
+	versions := make(map[string]bool)
+	err = conn.ExecQuery(ctx, &op, func(row Hash) bool {
+		version := row["version"]
+		if b, ok := version.([]byte); ok {
+			version = string(b)
 		}
+		versions[fmt.Sprint(version)] = true
+		return true
+	})
+	return versions, err
+}
 
-		for _, table := range m.tables {
-			err = conn.CreateTable(context.TODO(), &table)
-			if err != nil {
+func insertVersion(ctx context.Context, conn Conn, version string) error {
+	_, err := conn.ExecInsert(ctx, &InsertOperation{
+		TableName: SchemaMigrationsName,
+		ColumnValues: []ColumnValue{
+			{Name: "version", Type: new(String), Value: version},
+			{Name: "created_at", Type: new(DateTime), Value: time.Now()},
+		},
+	})
+	return err
+}
+
+func deleteVersion(ctx context.Context, conn Conn, version string) error {
+	return conn.ExecDelete(ctx, &DeleteOperation{
+		TableName:  SchemaMigrationsName,
+		PrimaryKey: "version",
+		Value:      version,
+	})
+}
+
+// migrate applies the migration, unless the version of the migration is
+// already stored in the schema migrations table.
+func (reg *Registry) migrate(ctx context.Context, m *M) error {
+	ctx = ContextWithSchemaChange(ctx)
+	return m.connections.Transaction(ctx, m.connectionName, func() error {
+		conn, err := m.connections.RetrieveConnection(m.connectionName)
+		if err != nil {
+			return err
+		}
+
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if versions == nil {
+			if err = createSchemaMigrations(ctx, conn); err != nil {
 				return err
 			}
 		}
+		if versions[m.id] {
+			return nil
+		}
 
-		for owner := range m.references {
-			err = conn.AddForeignKey(context.TODO(), owner, m.references[owner])
-			if err != nil {
+		for _, apply := range m.applyOperations() {
+			if err = apply(ctx, conn); err != nil {
 				return err
 			}
 		}
-		return nil
+		return insertVersion(ctx, conn, m.id)
 	})
+}
 
+// isApplied returns true, when the version of the migration is stored in the
+// schema migrations table.
+func (reg *Registry) isApplied(ctx context.Context, m *M) (bool, error) {
+	conn, err := m.connections.RetrieveConnection(m.connectionName)
 	if err != nil {
-		panic(err)
+		return false, err
+	}
+
+	versions, err := appliedVersions(ctx, conn)
+	return versions[m.id], err
+}
+
+func (reg *Registry) rollback(ctx context.Context, steps int) (reverted []*M, err error) {
+	migrations := reg.sortedMigrations()
+
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		applied, err := reg.isApplied(ctx, migrations[i])
+		if err != nil {
+			return reverted, err
+		}
+		if !applied {
+			continue
+		}
+		if err = reg.revert(ctx, migrations[i]); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migrations[i])
 	}
+	return reverted, nil
+}
+
+func (reg *Registry) revert(ctx context.Context, m *M) error {
+	ops, err := m.revertOperations()
+	if err != nil {
+		return err
+	}
+
+	ctx = ContextWithSchemaChange(ctx)
+	return m.connections.Transaction(ctx, m.connectionName, func() error {
+		conn, err := m.connections.RetrieveConnection(m.connectionName)
+		if err != nil {
+			return err
+		}
+
+		for _, revert := range ops {
+			if err = revert(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		return deleteVersion(ctx, conn, m.id)
+	})
 }
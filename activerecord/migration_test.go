@@ -1,7 +1,10 @@
 package activerecord
 
 import (
+	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -48,3 +51,660 @@ func TestMigrate_AddForeignKey(t *testing.T) {
 	require.Len(t, targets, 1)
 	require.Equal(t, targets[0].Attribute("value"), int64(43))
 }
+
+func TestMigrate_ForeignKeyOptions(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+		})
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+			t.Int64("author_id")
+			t.Int64("editor_id")
+			t.ForeignKey("authors", OnDelete(Cascade))
+			t.ForeignKey("authors",
+				ReferenceColumn("editor_id"),
+				ConstraintName("fk_books_editor"),
+				OnDelete(Nullify),
+			)
+		})
+	})
+
+	foreignKeys := func() []ForeignKeyDefinition {
+		conn, err := reg.RetrieveConnection("primary")
+		require.NoError(t, err)
+
+		fks, err := conn.ForeignKeys(context.TODO(), "books")
+		require.NoError(t, err)
+		return fks
+	}
+
+	fks := foreignKeys()
+	require.Len(t, fks, 2)
+	require.ElementsMatch(t, []ForeignKeyDefinition{
+		{
+			Name: "fk_books_on_author_id", TableName: "books", Column: "author_id",
+			ReferencedTable: "authors", PrimaryKey: "id", OnDelete: Cascade, OnUpdate: NoAction,
+		},
+		{
+			Name: "fk_books_editor", TableName: "books", Column: "editor_id",
+			ReferencedTable: "authors", PrimaryKey: "id", OnDelete: Nullify, OnUpdate: NoAction,
+		},
+	}, fks)
+
+	var schema strings.Builder
+	require.NoError(t, reg.DumpSchema(&schema, SchemaGo))
+	require.Contains(t, schema.String(),
+		`t.ForeignKey("authors", activerecord.OnDelete(activerecord.Cascade))`)
+	require.Contains(t, schema.String(), `t.ForeignKey("authors", `+
+		`activerecord.ReferenceColumn("editor_id"), `+
+		`activerecord.ConstraintName("fk_books_editor"), `+
+		`activerecord.OnDelete(activerecord.Nullify))`)
+
+	Author := reg.New("author")
+	Book := reg.New("book")
+
+	lem := Author.Create(Hash{"name": "Stanislaw Lem"}).Unwrap()
+	strugatsky := Author.Create(Hash{"name": "Arkady Strugatsky"}).Unwrap()
+	book := Book.Create(Hash{
+		"title": "Solaris", "author_id": lem.ID(), "editor_id": strugatsky.ID(),
+	}).Unwrap()
+
+	// Alteration of the referenced table keeps references, the rebuild
+	// of the table does not trigger referential actions.
+	reg.Migrate(t.Name()+"_1_alter", func(m *M) {
+		m.AddColumn("authors", "bio", new(String))
+		m.ChangeColumn("authors", "name", new(String), NotNull())
+		m.RemoveColumn("authors", "bio", new(String))
+	})
+	book = Book.Find(book.ID()).Unwrap()
+	require.Equal(t, lem.ID(), book.Attribute("author_id"))
+	require.Equal(t, strugatsky.ID(), book.Attribute("editor_id"))
+
+	// Deletion of the editor nullifies the reference.
+	_, err = strugatsky.Delete()
+	require.NoError(t, err)
+	book = Book.Find(book.ID()).Unwrap()
+	require.Nil(t, book.Attribute("editor_id"))
+
+	// Deletion of the author deletes the book.
+	_, err = lem.Delete()
+	require.NoError(t, err)
+	require.True(t, errors.Is(Book.Find(book.ID()).Err(), new(ErrRecordNotFound)))
+
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.RemoveForeignKey("books", "authors", ReferenceColumn("editor_id"))
+	})
+	require.Len(t, foreignKeys(), 1)
+
+	// Rollback restores the foreign key.
+	require.NoError(t, reg.Rollback(1))
+	require.Len(t, foreignKeys(), 2)
+}
+
+func TestMigrate_Rollback(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	tableExists := func(tableName string) bool {
+		conn, err := reg.RetrieveConnection("primary")
+		require.NoError(t, err)
+
+		_, err = conn.ColumnDefinitions(context.TODO(), tableName)
+		return err == nil
+	}
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+		})
+	})
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+		})
+	})
+	reg.Migrate(t.Name()+"_3", func(m *M) {
+		m.Up(func(m *M) {
+			m.DropTable("books")
+		})
+		m.Down(func(m *M) {
+			m.CreateTable("books", func(t *Table) {
+				t.String("title")
+			})
+		})
+	})
+	require.True(t, tableExists("authors"))
+	require.False(t, tableExists("books"))
+
+	// Rollback of the third migration creates the table, rollback of the
+	// second migration drops it.
+	require.NoError(t, reg.Rollback(1))
+	require.True(t, tableExists("books"))
+	require.NoError(t, reg.Rollback(1))
+	require.False(t, tableExists("books"))
+
+	SchemaMigration := reg.New("schema_migration")
+	migrations, err := SchemaMigration.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	require.Equal(t, t.Name()+"_1", migrations[0].ID())
+
+	Author := reg.New("author")
+	Author.Create(Hash{"name": "Stanislaw Lem"}).Expect("author was not created")
+
+	// Redo re-creates the table, therefore all records are gone.
+	require.NoError(t, reg.Redo(1))
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 0)
+
+	reg.Migrate(t.Name()+"_4", func(m *M) {
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+			t.References("authors")
+		})
+	})
+	reg.Migrate(t.Name()+"_5", func(m *M) {
+		m.AddForeignKey("books", "authors")
+	})
+
+	foreignKeys := func() []ForeignKeyDefinition {
+		conn, err := reg.RetrieveConnection("primary")
+		require.NoError(t, err)
+
+		fks, err := conn.ForeignKeys(context.TODO(), "books")
+		require.NoError(t, err)
+		return fks
+	}
+
+	require.Len(t, foreignKeys(), 1)
+	require.NoError(t, reg.Rollback(1))
+	require.Len(t, foreignKeys(), 0)
+
+	reg.Migrate(t.Name()+"_6", func(m *M) {
+		m.Execute(`UPDATE "books" SET "title" = 'untitled'`)
+	})
+
+	err = reg.Rollback(1)
+	require.True(t, errors.Is(err, new(ErrIrreversibleMigration)))
+	require.True(t, tableExists("books"))
+}
+
+func TestMigrate_AlterTable(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+		})
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+			t.Int64("year")
+			t.References("authors")
+		})
+		m.AddForeignKey("books", "authors")
+	})
+
+	author := reg.New("author").Create(Hash{"name": "Frank Herbert"}).Unwrap()
+	reg.New("book").Create(Hash{
+		"title": "Dune", "year": 1965, "author_id": author.ID(),
+	}).Expect("book was not created")
+
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.AddColumn("authors", "born", new(Int64))
+		m.RenameColumn("authors", "name", "full_name")
+		m.RemoveColumn("books", "year", new(Int64))
+		m.ChangeColumn("books", "title", new(DateTime))
+	})
+
+	conn, err := reg.RetrieveConnection("primary")
+	require.NoError(t, err)
+
+	columns, err := conn.ColumnDefinitions(context.TODO(), "books")
+	require.NoError(t, err)
+	require.Len(t, columns, 3)
+	for _, column := range columns {
+		require.NotEqual(t, "year", column.Name)
+		if column.Name == "title" {
+			require.IsType(t, new(DateTime), column.Type)
+		}
+	}
+
+	Author := reg.New("author")
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+	require.Equal(t, "Frank Herbert", authors[0].Attribute("full_name"))
+	require.Nil(t, authors[0].Attribute("born"))
+
+	// Foreign key is preserved after the rebuild of the table.
+	err = reg.Transaction(context.TODO(), func() error {
+		return reg.New("book").Create(Hash{"author_id": 42}).Err()
+	})
+	require.Error(t, err)
+
+	// Change of the column type cannot be reverted.
+	err = reg.Rollback(1)
+	require.True(t, errors.Is(err, new(ErrIrreversibleMigration)))
+
+	reg.Migrate(t.Name()+"_3", func(m *M) {
+		m.RenameColumn("authors", "full_name", "pen_name")
+		m.RemoveColumn("authors", "born", new(Int64))
+	})
+	require.NoError(t, reg.Rollback(1))
+
+	authors, err = reg.New("author").All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+	require.Equal(t, "Frank Herbert", authors[0].Attribute("full_name"))
+	require.True(t, authors[0].HasAttribute("born"))
+}
+
+func TestMigrate_AlterReferencedTable(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+			t.String("bio")
+		})
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+			t.Int64("author_id")
+			t.ForeignKey("authors", OnDelete(Cascade))
+		})
+	})
+
+	author := reg.New("author").Create(Hash{"name": "Frank Herbert"}).Unwrap()
+	reg.New("book").Create(Hash{
+		"title": "Dune", "author_id": author.ID(),
+	}).Expect("book was not created")
+
+	booksCount := func() int {
+		books, err := reg.New("book").All().ToA()
+		require.NoError(t, err)
+		return len(books)
+	}
+
+	// Rebuild of the referenced table must not delete rows of tables
+	// referencing it.
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.RemoveColumn("authors", "bio", new(String))
+	})
+	require.Equal(t, 1, booksCount())
+
+	conn, err := reg.RetrieveConnection("primary")
+	require.NoError(t, err)
+
+	// The same is true for the rebuild outside of migrations.
+	err = conn.ChangeColumn(context.TODO(), "authors", ColumnDefinition{
+		Name: "name", Type: new(String), NotNull: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, booksCount())
+
+	// Foreign keys are still maintained after the rebuild.
+	_, err = author.Delete()
+	require.NoError(t, err)
+	require.Equal(t, 0, booksCount())
+
+	// Violated foreign keys are reported on commit of the migration.
+	reg.Register(t.Name()+"_3", func(m *M) {
+		m.Execute(`INSERT INTO "books" ("title", "author_id") VALUES ('Dune', 42)`)
+	})
+	require.Error(t, reg.Migrator().Migrate(context.TODO()))
+	require.Equal(t, 0, booksCount())
+}
+
+func TestMigrate_AddIndex(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("users", func(t *Table) {
+			t.String("email")
+			t.DateTime("deleted_at")
+			t.Index("email", Unique(), Partial("deleted_at IS NULL"))
+		})
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+			t.Int64("year")
+			t.References("users")
+		})
+	})
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.AddIndex("books", "user_id", Columns("title"), Unique())
+		m.AddIndex("books", "year", IndexName("books_year"))
+	})
+
+	conn, err := reg.RetrieveConnection("primary")
+	require.NoError(t, err)
+
+	indexes, err := conn.Indexes(context.TODO(), "users")
+	require.NoError(t, err)
+	require.Equal(t, []IndexDefinition{{
+		Name:      "index_users_on_email",
+		TableName: "users",
+		Columns:   []string{"email"},
+		Unique:    true,
+		Where:     "deleted_at IS NULL",
+	}}, indexes)
+
+	indexes, err = conn.Indexes(context.TODO(), "books")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []IndexDefinition{{
+		Name:      "index_books_on_user_id_and_title",
+		TableName: "books",
+		Columns:   []string{"user_id", "title"},
+		Unique:    true,
+	}, {
+		Name:      "books_year",
+		TableName: "books",
+		Columns:   []string{"year"},
+	}}, indexes)
+
+	User := reg.New("user")
+	User.Create(Hash{"email": "bob@example.com"}).Expect("user was not created")
+
+	user := User.Create(Hash{"email": "bob@example.com"})
+	require.True(t, errors.Is(user.Err(), new(ErrRecordNotUnique)))
+
+	// Indexes of removed columns are dropped, the rest of indexes are
+	// restored after the rebuild of the table.
+	reg.Migrate(t.Name()+"_3", func(m *M) {
+		m.RemoveColumn("books", "year", new(Int64))
+	})
+
+	indexes, err = conn.Indexes(context.TODO(), "books")
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	require.Equal(t, "index_books_on_user_id_and_title", indexes[0].Name)
+
+	reg.Migrate(t.Name()+"_4", func(m *M) {
+		m.RemoveIndex("books", "user_id", Columns("title"), Unique())
+	})
+
+	indexes, err = conn.Indexes(context.TODO(), "books")
+	require.NoError(t, err)
+	require.Len(t, indexes, 0)
+
+	require.NoError(t, reg.Rollback(1))
+
+	indexes, err = conn.Indexes(context.TODO(), "books")
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	require.True(t, indexes[0].Unique)
+
+	// Indexes on expressions keep expressions and survive the rebuild.
+	reg.Migrate(t.Name()+"_5", func(m *M) {
+		m.Execute(`CREATE INDEX "index_users_on_lower_email" ON "users" (lower("email"), deleted_at)`)
+		m.AddColumn("users", "name", new(String))
+		m.RemoveColumn("users", "name", new(String))
+	})
+
+	expressionIndex := IndexDefinition{
+		Name:      "index_users_on_lower_email",
+		TableName: "users",
+		Columns:   []string{`(lower("email"))`, "deleted_at"},
+	}
+
+	indexes, err = conn.Indexes(context.TODO(), "users")
+	require.NoError(t, err)
+	require.Contains(t, indexes, expressionIndex)
+
+	// Indexes of other tables are not removed.
+	reg.Register(t.Name()+"_6", func(m *M) {
+		m.RemoveIndex("books", "email", IndexName("index_users_on_lower_email"))
+	})
+	require.Error(t, reg.Migrator().Migrate(context.TODO()))
+
+	indexes, err = conn.Indexes(context.TODO(), "users")
+	require.NoError(t, err)
+	require.Contains(t, indexes, expressionIndex)
+}
+
+func TestMigrate_ColumnOptions(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate(t.Name()+"_1", func(m *M) {
+		m.CreateTable("articles", func(t *Table) {
+			t.String("title", NotNull(), Limit(100))
+			t.String("status", NotNull(), Default("draft"))
+			t.Float64("price", Precision(10), Scale(2), Check("price >= 0"))
+			t.Int64("views", Default(0))
+			t.DateTime("created_at", NotNull(), DefaultSQL("CURRENT_TIMESTAMP"))
+		})
+	})
+
+	assertColumns := func() {
+		conn, err := reg.RetrieveConnection("primary")
+		require.NoError(t, err)
+
+		columns, err := conn.ColumnDefinitions(context.TODO(), "articles")
+		require.NoError(t, err)
+
+		definitions := make(map[string]ColumnDefinition, len(columns))
+		for _, column := range columns {
+			column.Type = nil
+			definitions[column.Name] = column
+		}
+
+		require.Equal(t, ColumnDefinition{Name: "title", NotNull: true, Limit: 100}, definitions["title"])
+		require.Equal(t, ColumnDefinition{Name: "status", NotNull: true, Default: "draft"}, definitions["status"])
+		require.Equal(t, ColumnDefinition{
+			Name: "price", Precision: 10, Scale: 2, Check: "price >= 0",
+		}, definitions["price"])
+		require.Equal(t, ColumnDefinition{
+			Name: "created_at", NotNull: true, DefaultSQL: "CURRENT_TIMESTAMP",
+		}, definitions["created_at"])
+	}
+
+	assertColumns()
+
+	Article := reg.New("article")
+
+	article := Article.New().Unwrap()
+	require.Equal(t, "draft", article.Attribute("status"))
+	require.Equal(t, int64(0), article.Attribute("views"))
+
+	// Presence of the title is derived from NOT NULL constraint.
+	_, err = article.Insert()
+	require.Error(t, err)
+	require.IsType(t, ErrValidation{}, err)
+
+	article = Article.Create(Hash{"title": "Solaris", "price": 9.99}).Unwrap()
+
+	article = Article.Find(article.ID()).Unwrap()
+	require.Equal(t, "draft", article.Attribute("status"))
+	require.NotNil(t, article.Attribute("created_at"))
+
+	err = Article.Create(Hash{"title": "Eden", "price": -1.0}).Err()
+	require.Error(t, err)
+
+	// Column options are preserved after the rebuild of the table.
+	reg.Migrate(t.Name()+"_2", func(m *M) {
+		m.RemoveColumn("articles", "views")
+	})
+	assertColumns()
+}
+
+func TestDumpSchema(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	reg.Migrate("1", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name", NotNull(), Limit(100))
+			t.Index("name", Unique())
+		})
+	})
+	reg.Migrate("2", func(m *M) {
+		m.CreateTable("books", func(t *Table) {
+			t.String("title", Default("untitled"))
+			t.Float64("price", Precision(10), Scale(2), Check("price >= 0"))
+			t.Enum("status", []string{"draft", "it's"}, Default("draft"))
+			t.References("authors", References{ForeignKey: true})
+			t.Index("author_id", Columns("title"))
+		})
+	})
+
+	var schema, structure strings.Builder
+	require.NoError(t, reg.DumpSchema(&schema, SchemaGo))
+	require.NoError(t, reg.DumpSchema(&structure, SchemaSQL))
+
+	require.Contains(t, schema.String(), "var Versions = []string{\n\t\"1\",\n\t\"2\",\n}")
+	require.Contains(t, schema.String(),
+		`t.String("name", activerecord.NotNull(), activerecord.Limit(100))`)
+	require.Contains(t, schema.String(), `t.Index("name", activerecord.Unique())`)
+	require.Contains(t, schema.String(), `t.ForeignKey("authors")`)
+	require.Contains(t, schema.String(),
+		`t.Enum("status", []string{"draft", "it's"}, activerecord.Default("draft"))`)
+	require.Contains(t, schema.String(),
+		`t.Index("author_id", activerecord.Columns("title"))`)
+
+	// Referenced tables are defined before referencing tables.
+	require.Less(t,
+		strings.Index(structure.String(), `CREATE TABLE "authors"`),
+		strings.Index(structure.String(), `CREATE TABLE "books"`),
+	)
+	require.Contains(t, structure.String(),
+		`INSERT INTO "schema_migrations" ("version") VALUES ('2');`)
+
+	inspect := func(reg *Registry) (columns []ColumnDefinition, indexes []IndexDefinition) {
+		conn, err := reg.RetrieveConnection("primary")
+		require.NoError(t, err)
+
+		for _, tableName := range []string{"authors", "books"} {
+			cols, err := conn.ColumnDefinitions(context.TODO(), tableName)
+			require.NoError(t, err)
+			idxs, err := conn.Indexes(context.TODO(), tableName)
+			require.NoError(t, err)
+
+			columns = append(columns, cols...)
+			indexes = append(indexes, idxs...)
+		}
+		return columns, indexes
+	}
+
+	columns, indexes := inspect(reg)
+
+	t.Run("LoadStructure", func(t *testing.T) {
+		database := strings.ReplaceAll(t.Name(), "/", "_")
+
+		loaded := NewRegistry()
+		_, err := loaded.EstablishConnection(DatabaseConfig{
+			Adapter: "sqlite3", Database: database,
+		})
+		require.NoError(t, err)
+
+		defer os.Remove(database)
+		defer loaded.RemoveConnection("primary")
+
+		require.NoError(t, loaded.LoadStructure(strings.NewReader(structure.String())))
+
+		loadedColumns, loadedIndexes := inspect(loaded)
+		require.Equal(t, columns, loadedColumns)
+		require.Equal(t, indexes, loadedIndexes)
+
+		conn, err := loaded.RetrieveConnection("primary")
+		require.NoError(t, err)
+		versions, err := schemaVersions(context.TODO(), conn)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2"}, versions)
+	})
+
+	t.Run("LoadSchema", func(t *testing.T) {
+		database := strings.ReplaceAll(t.Name(), "/", "_")
+
+		loaded := NewRegistry()
+		_, err := loaded.EstablishConnection(DatabaseConfig{
+			Adapter: "sqlite3", Database: database,
+		})
+		require.NoError(t, err)
+
+		defer os.Remove(database)
+		defer loaded.RemoveConnection("primary")
+
+		// The same definition as dumped into the Go schema.
+		define := func(m *M) {
+			m.CreateTable("authors", func(t *Table) {
+				t.String("name", NotNull(), Limit(100))
+				t.Index("name", Unique())
+			})
+			m.CreateTable("books", func(t *Table) {
+				t.String("title", Default("untitled"))
+				t.Float64("price", Precision(10), Scale(2), Check("price >= 0"))
+				t.Enum("status", []string{"draft", "it's"}, Default("draft"))
+				t.References("authors", References{ForeignKey: true})
+				t.Index("author_id", Columns("title"))
+			})
+		}
+		require.NoError(t, loaded.LoadSchema(define, "1", "2"))
+
+		loadedColumns, loadedIndexes := inspect(loaded)
+		require.Equal(t, columns, loadedColumns)
+		require.Equal(t, indexes, loadedIndexes)
+
+		conn, err := loaded.RetrieveConnection("primary")
+		require.NoError(t, err)
+		versions, err := schemaVersions(context.TODO(), conn)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2"}, versions)
+	})
+}
@@ -0,0 +1,148 @@
+package activerecord
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrUnknownMigration is returned when the target version is not registered.
+type ErrUnknownMigration struct {
+	Version string
+}
+
+func (e *ErrUnknownMigration) Is(target error) bool {
+	_, ok := target.(*ErrUnknownMigration)
+	return ok
+}
+
+func (e *ErrUnknownMigration) Error() string {
+	return fmt.Sprintf("migration %q is not registered", e.Version)
+}
+
+// MigrationStatus describes the state of the registered migration.
+type MigrationStatus struct {
+	Version string
+	Applied bool
+}
+
+func (s MigrationStatus) String() string {
+	if s.Applied {
+		return fmt.Sprintf("applied %s", s.Version)
+	}
+	return fmt.Sprintf("pending %s", s.Version)
+}
+
+// Migrator applies and reverts registered migrations in the order of their
+// versions. Versions are compared as strings, therefore zero-padded numbers or
+// timestamps should be used as prefixes of migration versions.
+//
+//	migrator := activerecord.NewMigrator()
+//	err := migrator.MigrateTo(ctx, "002_create_books")
+//
+// Use DryRun context to print SQL statements of the migrations instead of
+// executing them:
+//
+//	err := migrator.Migrate(activerecord.DryRun(ctx, os.Stdout))
+type Migrator struct {
+	reg *Registry
+}
+
+// NewMigrator returns a migrator of migrations registered within the default
+// registry.
+func NewMigrator() *Migrator {
+	return defaultRegistry.Migrator()
+}
+
+// Migrator returns a migrator of migrations registered within the registry.
+func (reg *Registry) Migrator() *Migrator {
+	return &Migrator{reg: reg}
+}
+
+// Status returns statuses of registered migrations ordered by versions.
+func (mr *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	// Migrations might be applied to different databases, query versions of
+	// each database only once.
+	applied := make(map[string]map[string]bool)
+
+	migrations := mr.reg.sortedMigrations()
+	statuses := make([]MigrationStatus, 0, len(migrations))
+
+	for _, m := range migrations {
+		versions, ok := applied[m.connectionName]
+		if !ok {
+			conn, err := mr.reg.connections.RetrieveConnection(m.connectionName)
+			if err != nil {
+				return nil, err
+			}
+			if versions, err = appliedVersions(ctx, conn); err != nil {
+				return nil, err
+			}
+			applied[m.connectionName] = versions
+		}
+		statuses = append(statuses, MigrationStatus{Version: m.id, Applied: versions[m.id]})
+	}
+	return statuses, nil
+}
+
+// Migrate applies all pending migrations.
+func (mr *Migrator) Migrate(ctx context.Context) error {
+	for _, m := range mr.reg.sortedMigrations() {
+		if err := mr.reg.migrate(ctx, m); err != nil {
+			return fmt.Errorf("migration %q: %w", m.id, err)
+		}
+	}
+	return nil
+}
+
+// MigrateTo applies pending migrations up to the target version inclusively.
+func (mr *Migrator) MigrateTo(ctx context.Context, version string) error {
+	migrations := mr.reg.sortedMigrations()
+	if !hasMigration(migrations, version) {
+		return &ErrUnknownMigration{Version: version}
+	}
+
+	for _, m := range migrations {
+		if m.id > version {
+			break
+		}
+		if err := mr.reg.migrate(ctx, m); err != nil {
+			return fmt.Errorf("migration %q: %w", m.id, err)
+		}
+	}
+	return nil
+}
+
+// RollbackTo reverts applied migrations with versions greater than the target
+// version, the target migration remains applied. Empty version reverts all
+// applied migrations.
+func (mr *Migrator) RollbackTo(ctx context.Context, version string) error {
+	migrations := mr.reg.sortedMigrations()
+	if version != "" && !hasMigration(migrations, version) {
+		return &ErrUnknownMigration{Version: version}
+	}
+
+	for i := len(migrations) - 1; i >= 0 && migrations[i].id > version; i-- {
+		m := migrations[i]
+
+		applied, err := mr.reg.isApplied(ctx, m)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			continue
+		}
+		if err = mr.reg.revert(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasMigration(migrations []*M, version string) bool {
+	for _, m := range migrations {
+		if m.id == version {
+			return true
+		}
+	}
+	return false
+}
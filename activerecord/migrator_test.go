@@ -0,0 +1,106 @@
+package activerecord
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.EstablishConnection(DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name(),
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name())
+	defer reg.RemoveConnection("primary")
+
+	// Migrations are registered out of order.
+	reg.Register("003_add_books_year", func(m *M) {
+		m.AddColumn("books", "year", new(Int64))
+	})
+	reg.Register("001_create_authors", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+		})
+	})
+	reg.Register("002_create_books", func(m *M) {
+		m.CreateTable("books", func(t *Table) {
+			t.String("title")
+		})
+	})
+
+	ctx := context.TODO()
+	migrator := reg.Migrator()
+
+	status := func() []MigrationStatus {
+		statuses, err := migrator.Status(ctx)
+		require.NoError(t, err)
+		return statuses
+	}
+
+	require.Equal(t, []MigrationStatus{
+		{Version: "001_create_authors"},
+		{Version: "002_create_books"},
+		{Version: "003_add_books_year"},
+	}, status())
+
+	// Dry run prints statements without applying them.
+	var sql strings.Builder
+	err = migrator.MigrateTo(DryRun(ctx, &sql), "001_create_authors")
+	require.NoError(t, err)
+	require.Contains(t, sql.String(), `CREATE TABLE "authors"`)
+	require.Contains(t, sql.String(), `INSERT INTO "schema_migrations"`)
+	require.False(t, status()[0].Applied)
+
+	err = migrator.MigrateTo(ctx, "002_create_books")
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStatus{
+		{Version: "001_create_authors", Applied: true},
+		{Version: "002_create_books", Applied: true},
+		{Version: "003_add_books_year"},
+	}, status())
+
+	err = migrator.MigrateTo(ctx, "004_unknown")
+	require.True(t, errors.Is(err, new(ErrUnknownMigration)))
+
+	err = migrator.Migrate(ctx)
+	require.NoError(t, err)
+	require.True(t, status()[2].Applied)
+
+	err = migrator.RollbackTo(ctx, "001_create_authors")
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStatus{
+		{Version: "001_create_authors", Applied: true},
+		{Version: "002_create_books"},
+		{Version: "003_add_books_year"},
+	}, status())
+
+	err = migrator.RollbackTo(ctx, "")
+	require.NoError(t, err)
+	require.False(t, status()[0].Applied)
+
+	// Failed migrations are reported as errors and the transaction is
+	// rolled back.
+	reg.Register("004_create_authors", func(m *M) {
+		m.CreateTable("authors", func(t *Table) {
+			t.String("name")
+		})
+	})
+
+	err = migrator.Migrate(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `migration "004_create_authors"`)
+	require.Equal(t, []MigrationStatus{
+		{Version: "001_create_authors", Applied: true},
+		{Version: "002_create_books", Applied: true},
+		{Version: "003_add_books_year", Applied: true},
+		{Version: "004_create_authors"},
+	}, status())
+}
@@ -0,0 +1,314 @@
+package activerecord
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+// DestroyAttributeName is the key of nested attributes, which marks the
+// associated record for deletion, when the association allows it.
+const DestroyAttributeName = "_destroy"
+
+// NestedAttributes describes the association, records of which are created,
+// updated and deleted through attributes of the owner record.
+type NestedAttributes struct {
+	AssociationName string
+	AllowDestroy    bool
+}
+
+// NestedAttributesOption configures nested attributes of the association.
+type NestedAttributesOption func(*NestedAttributes)
+
+// AllowDestroy allows to delete associated records by nested attributes with
+// DestroyAttributeName key set to true.
+func AllowDestroy() NestedAttributesOption {
+	return func(n *NestedAttributes) { n.AllowDestroy = true }
+}
+
+// nestedRecord is an associated record built from nested attributes.
+type nestedRecord struct {
+	path       string
+	rec        *ActiveRecord
+	foreignKey string
+	persisted  bool
+	destroy    bool
+}
+
+// save writes the associated record referencing the owner.
+func (n *nestedRecord) save(owner *ActiveRecord) (err error) {
+	if n.destroy {
+		_, err = n.rec.Delete()
+		return err
+	}
+	if err = n.rec.AssignAttribute(n.foreignKey, owner.ID()); err != nil {
+		return err
+	}
+	if n.persisted {
+		_, err = n.rec.Update()
+	} else {
+		_, err = n.rec.Insert()
+	}
+	return err
+}
+
+// ReflectOnAllNestedAttributes returns associations accepting nested
+// attributes ordered by the name of association.
+func (rel *Relation) ReflectOnAllNestedAttributes() []NestedAttributes {
+	nested := make([]NestedAttributes, 0, len(rel.nested))
+	for _, n := range rel.nested {
+		nested = append(nested, n)
+	}
+	sort.Slice(nested, func(i, j int) bool {
+		return nested[i].AssociationName < nested[j].AssociationName
+	})
+	return nested
+}
+
+// splitNestedAttributes separates nested attributes of associations from
+// attributes of the record.
+func (rel *Relation) splitNestedAttributes(params map[string]interface{}) (
+	attrs map[string]interface{}, nested Hash,
+) {
+	if rel == nil || len(rel.nested) == 0 {
+		return params, nil
+	}
+
+	attrs = make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if _, ok := rel.nested[name]; !ok {
+			attrs[name] = value
+			continue
+		}
+		if nested == nil {
+			nested = make(Hash)
+		}
+		nested[name] = value
+	}
+	return attrs, nested
+}
+
+// buildNestedRecords returns associated records built from nested attributes
+// of the owner. Records with identifiers are found among the records of the
+// owner, other records are initialized.
+func (rel *Relation) buildNestedRecords(owner *ActiveRecord, nested Hash) ([]nestedRecord, error) {
+	names := make([]string, 0, len(nested))
+	for name := range nested {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var records []nestedRecord
+	for _, name := range names {
+		assoc := rel.associations.keys[name].(*HasMany)
+		targets, err := assoc.reflection.Reflection(assoc.targetName)
+		if err != nil {
+			return nil, err
+		}
+		targets = targets.WithContext(owner.Context())
+
+		items, err := nestedAttributesList(name, nested[name])
+		if err != nil {
+			return nil, err
+		}
+
+		for i, item := range items {
+			n := nestedRecord{
+				path:       fmt.Sprintf("%s[%d]", name, i),
+				foreignKey: assoc.AssociationForeignKey(),
+			}
+
+			attrs := make(Hash, len(item))
+			for attrName, value := range item {
+				attrs[attrName] = value
+			}
+			delete(attrs, DestroyAttributeName)
+			n.destroy = rel.nested[name].AllowDestroy && item[DestroyAttributeName] == true
+
+			id, ok := attrs[targets.PrimaryKey()]
+			delete(attrs, targets.PrimaryKey())
+
+			switch {
+			case ok && id != nil:
+				// Only records of the owner could be updated or deleted.
+				var rec *ActiveRecord
+				if owner.ID() != nil {
+					res := targets.Where(n.foreignKey, owner.ID()).Where(targets.PrimaryKey(), id).First()
+					if res.IsErr() {
+						return nil, res.Err()
+					}
+					rec = res.Ok().UnwrapOr(nil)
+				}
+				if rec == nil {
+					return nil, fmt.Errorf("%s: %w", n.path, &ErrRecordNotFound{
+						PrimaryKey: targets.PrimaryKey(), ID: id,
+					})
+				}
+				n.rec, n.persisted = rec, true
+				if !n.destroy {
+					err = n.rec.AssignAttributes(attrs)
+				}
+			case n.destroy:
+				// New records marked for deletion are never inserted.
+				continue
+			default:
+				n.rec, err = targets.Initialize(attrs)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", n.path, err)
+			}
+			records = append(records, n)
+		}
+	}
+	return records, nil
+}
+
+// nestedAttributesList returns attributes of associated records, given as
+// a list of hashes.
+func nestedAttributesList(name string, value interface{}) ([]Hash, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case []Hash:
+		return value, nil
+	case []map[string]interface{}:
+		items := make([]Hash, 0, len(value))
+		for _, item := range value {
+			items = append(items, item)
+		}
+		return items, nil
+	case []interface{}:
+		items := make([]Hash, 0, len(value))
+		for i, item := range value {
+			switch item := item.(type) {
+			case Hash:
+				items = append(items, item)
+			case map[string]interface{}:
+				items = append(items, item)
+			default:
+				return nil, ErrInvalidValue{
+					AttrName: fmt.Sprintf("%s[%d]", name, i), Message: "is not a hash", Value: item,
+				}
+			}
+		}
+		return items, nil
+	default:
+		return nil, ErrInvalidValue{AttrName: name, Message: "is not a list of hashes", Value: value}
+	}
+}
+
+// validateNested validates the owner and associated records, errors of the
+// associated records are addressed by nested paths, e.g. "books[0].title".
+func validateNested(owner *ActiveRecord, records []nestedRecord) error {
+	var errs Errors
+
+	if err := owner.Validate(); err != nil {
+		var verr ErrValidation
+		if !errors.As(err, &verr) {
+			return err
+		}
+		for key, keyErrors := range verr.Errors.errors {
+			for _, e := range keyErrors {
+				errs.Add(key, e)
+			}
+		}
+	}
+
+	for _, n := range records {
+		if n.destroy {
+			continue
+		}
+
+		// The reference to the new owner is assigned after the owner insertion.
+		if owner.ID() != nil {
+			if err := n.rec.AssignAttribute(n.foreignKey, owner.ID()); err != nil {
+				return err
+			}
+		}
+
+		err := n.rec.Validate()
+		if err == nil {
+			continue
+		}
+		var verr ErrValidation
+		if !errors.As(err, &verr) {
+			return err
+		}
+		for key, keyErrors := range verr.Errors.errors {
+			if key == n.foreignKey && owner.ID() == nil {
+				continue
+			}
+			path := n.path + "." + key
+			for _, e := range keyErrors {
+				errs.Add(path, nestedError(path, e))
+			}
+		}
+	}
+
+	if !errs.IsEmpty() {
+		return ErrValidation{Model: owner, Errors: errs}
+	}
+	return nil
+}
+
+// nestedError returns the error of the associated record attribute addressed
+// by the nested path.
+func nestedError(path string, err error) error {
+	if e, ok := err.(ErrInvalidValue); ok {
+		e.AttrName = path
+		return e
+	}
+	return ErrInvalidValue{AttrName: path, Message: err.Error()}
+}
+
+// saveNested saves the record along with associated records built from
+// nested attributes within a single transaction.
+func (r *ActiveRecord) saveNested(save func(*ActiveRecord) (*ActiveRecord, error)) (*ActiveRecord, error) {
+	rel := r.relation.WithContext(r.Context())
+
+	var saved *ActiveRecord
+	err := rel.connections.Transaction(r.Context(), rel.connectionName, func() error {
+		// Write the record through the connection of the transaction.
+		rec := r.Copy()
+		rec.conn = rel.Connection()
+		rec.nested = nil
+
+		records, err := rel.buildNestedRecords(rec, r.nested)
+		if err != nil {
+			return err
+		}
+		if err = validateNested(rec, records); err != nil {
+			return err
+		}
+
+		if rec, err = save(rec); err != nil {
+			return err
+		}
+		for i := range records {
+			if err = records[i].save(rec); err != nil {
+				return fmt.Errorf("%s: %w", records[i].path, err)
+			}
+		}
+
+		// Counter caches are changed by associated records in the database.
+		if len(rel.counterCacheColumns()) != 0 {
+			res := rel.Unscoped().Find(rec.ID())
+			if res.IsErr() {
+				return res.Err()
+			}
+			rec = res.Unwrap()
+		}
+
+		saved = rec
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.attributes = saved.attributes.copy()
+	r.nested = nil
+	return r.init(), nil
+}
@@ -2,6 +2,7 @@ package activerecord
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/activegraph/activegraph/activesupport"
@@ -22,23 +23,57 @@ type Persistence interface {
 	IsPersisted() bool
 }
 
+// Actions on conflict of inserted rows with existing ones.
+const (
+	OnDuplicateUpdate = "update"
+	OnDuplicateSkip   = "skip"
+)
+
 type InsertOperation struct {
-	TableName      string
-	ColumnValues   []ColumnValue
+	TableName    string
+	ColumnValues []ColumnValue
+
+	// Rows are values of multiple rows inserted with a single statement, when
+	// set ColumnValues are ignored. All rows must have the same columns.
+	Rows [][]ColumnValue
+
+	// OnDuplicate defines the action on conflict of inserted rows by columns
+	// of ConflictTarget (comma-separated). Empty action fails the insertion.
 	OnDuplicate    string
 	ConflictTarget string
+
+	// Returning is a column returned for each inserted row, when set the
+	// insertion returns values of the column as []interface{}. Rows skipped
+	// on conflict are not returned.
+	Returning string
 }
 
 type UpdateOperation struct {
 	TableName    string
 	PrimaryKey   string
 	ColumnValues []ColumnValue
+
+	// Where restricts updated rows, when the primary key is empty. Otherwise
+	// the row is updated by the primary key value within ColumnValues.
+	Where []Predicate
+
+	// Increments are columns incremented by the given values in place, so
+	// concurrent updates are not lost. NULL values are incremented from zero.
+	Increments []ColumnValue
+
+	// LockingColumn and LockingValue are set when the record is updated with
+	// optimistic locking. When no rows are updated, ErrStaleObject is expected.
+	LockingColumn string
+	LockingValue  interface{}
 }
 
 type DeleteOperation struct {
 	TableName  string
 	PrimaryKey string
 	Value      interface{}
+
+	// Where restricts deleted rows, when the primary key is empty.
+	Where []Predicate
 }
 
 type Dependency struct {
@@ -51,6 +86,10 @@ type QueryOperation struct {
 	Text    string
 	Args    []interface{}
 	Columns []string
+
+	// Lock is a row-level locking clause, adapters append it to the text of
+	// the query, when row-level locks are supported.
+	Lock LockMode
 }
 
 type ColumnValue struct {
@@ -64,6 +103,43 @@ type ColumnDefinition struct {
 	Type         Type
 	NotNull      bool
 	IsPrimaryKey bool
+
+	// Default is a literal default value of the column, DefaultSQL is an
+	// expression evaluated by the database, e.g. CURRENT_TIMESTAMP.
+	Default    interface{}
+	DefaultSQL string
+
+	// Limit is the maximum length of the string column, Precision and Scale
+	// define the number of digits of the numeric column.
+	Limit     int
+	Precision int
+	Scale     int
+
+	// Check is a condition of the check constraint of the column.
+	Check string
+}
+
+// IndexDefinition describes an index of the table. Partial indexes have
+// a condition in Where. Expressions of indexes on expressions are listed
+// within Columns wrapped in parentheses, e.g. "(lower(email))".
+type IndexDefinition struct {
+	Name      string
+	TableName string
+	Columns   []string
+	Unique    bool
+	Where     string
+}
+
+// ForeignKeyDefinition describes a foreign key constraint of the table, where
+// Column of the table references PrimaryKey of the ReferencedTable.
+type ForeignKeyDefinition struct {
+	Name            string
+	TableName       string
+	Column          string
+	ReferencedTable string
+	PrimaryKey      string
+	OnDelete        ReferentialAction
+	OnUpdate        ReferentialAction
 }
 
 type TransactionStatements interface {
@@ -77,14 +153,32 @@ type DatabaseStatements interface {
 	ExecUpdate(ctx context.Context, op *UpdateOperation) (err error)
 	ExecDelete(ctx context.Context, op *DeleteOperation) (err error)
 	ExecQuery(ctx context.Context, op *QueryOperation, cb func(activesupport.Hash) bool) (err error)
+
+	// JSONExtract returns an expression of the value at the path of keys
+	// within the JSON column.
+	JSONExtract(column string, path ...string) string
 }
 
 type SchemaStatements interface {
 	CreateTable(ctx context.Context, table *Table) error
-	AddForeignKey(ctx context.Context, owner, target string) error
+	DropTable(ctx context.Context, tableName string) error
+	AddColumn(ctx context.Context, tableName string, column ColumnDefinition) error
+	RemoveColumn(ctx context.Context, tableName, columnName string) error
+	RenameColumn(ctx context.Context, tableName, columnName, newColumnName string) error
+	ChangeColumn(ctx context.Context, tableName string, column ColumnDefinition) error
+	AddForeignKey(ctx context.Context, fk ForeignKeyDefinition) error
+	RemoveForeignKey(ctx context.Context, fk ForeignKeyDefinition) error
+	AddIndex(ctx context.Context, index IndexDefinition) error
+	RemoveIndex(ctx context.Context, tableName, indexName string) error
 
 	ColumnType(typeName string) (Type, error)
 	ColumnDefinitions(ctx context.Context, tableName string) ([]ColumnDefinition, error)
+	Indexes(ctx context.Context, tableName string) ([]IndexDefinition, error)
+	ForeignKeys(ctx context.Context, tableName string) ([]ForeignKeyDefinition, error)
+	Tables(ctx context.Context) ([]string, error)
+
+	// Execute executes an arbitrary SQL statement.
+	Execute(ctx context.Context, stmt string) error
 }
 
 type Conn interface {
@@ -92,6 +186,8 @@ type Conn interface {
 	DatabaseStatements
 	SchemaStatements
 
+	Ping(ctx context.Context) error
+	Stats() sql.DBStats
 	Close() error
 }
 
@@ -129,6 +225,10 @@ func (c *errConn) ExecQuery(context.Context, *QueryOperation, func(activesupport
 	return c.err
 }
 
+func (c *errConn) JSONExtract(column string, path ...string) string {
+	return column
+}
+
 // SchemaStatements
 func (c *errConn) ColumnType(typeName string) (Type, error) {
 	return nil, c.err
@@ -138,14 +238,70 @@ func (c *errConn) ColumnDefinitions(ctx context.Context, tableName string) ([]Co
 	return nil, c.err
 }
 
+func (c *errConn) Indexes(ctx context.Context, tableName string) ([]IndexDefinition, error) {
+	return nil, c.err
+}
+
+func (c *errConn) ForeignKeys(ctx context.Context, tableName string) ([]ForeignKeyDefinition, error) {
+	return nil, c.err
+}
+
+func (c *errConn) Tables(ctx context.Context) ([]string, error) {
+	return nil, c.err
+}
+
+func (c *errConn) Execute(ctx context.Context, stmt string) error {
+	return c.err
+}
+
 func (c *errConn) CreateTable(ctx context.Context, table *Table) error {
 	return c.err
 }
 
-func (c *errConn) AddForeignKey(ctx context.Context, owner, target string) error {
+func (c *errConn) DropTable(ctx context.Context, tableName string) error {
+	return c.err
+}
+
+func (c *errConn) AddColumn(ctx context.Context, tableName string, column ColumnDefinition) error {
+	return c.err
+}
+
+func (c *errConn) RemoveColumn(ctx context.Context, tableName, columnName string) error {
+	return c.err
+}
+
+func (c *errConn) RenameColumn(ctx context.Context, tableName, columnName, newColumnName string) error {
+	return c.err
+}
+
+func (c *errConn) ChangeColumn(ctx context.Context, tableName string, column ColumnDefinition) error {
+	return c.err
+}
+
+func (c *errConn) AddForeignKey(ctx context.Context, fk ForeignKeyDefinition) error {
+	return c.err
+}
+
+func (c *errConn) RemoveForeignKey(ctx context.Context, fk ForeignKeyDefinition) error {
+	return c.err
+}
+
+func (c *errConn) AddIndex(ctx context.Context, index IndexDefinition) error {
 	return c.err
 }
 
+func (c *errConn) RemoveIndex(ctx context.Context, tableName, indexName string) error {
+	return c.err
+}
+
+func (c *errConn) Ping(ctx context.Context) error {
+	return c.err
+}
+
+func (c *errConn) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
+
 func (c *errConn) Close() error {
 	return c.err
 }
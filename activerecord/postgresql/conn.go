@@ -2,6 +2,8 @@ package postgresql
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/activegraph/activegraph/activerecord"
 )
@@ -18,3 +20,18 @@ func (c *Conn) ExecInsert(ctx context.Context, op *activerecord.InsertOperation)
 ) {
 	return nil, nil
 }
+
+// JSONExtract returns the expression of the value at the path of keys within
+// the JSON column, the last key is extracted as text with ->> operator.
+func (c *Conn) JSONExtract(column string, path ...string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%q", column)
+	for i, key := range path {
+		op := "->"
+		if i == len(path)-1 {
+			op = "->>"
+		}
+		fmt.Fprintf(&buf, "%s'%s'", op, strings.ReplaceAll(key, "'", "''"))
+	}
+	return buf.String()
+}
@@ -10,6 +10,32 @@ type Predicate struct {
 	Args []interface{}
 }
 
+// ErrUnsupportedLock is returned when the adapter cannot lock rows in the given
+// mode within the current transaction.
+type ErrUnsupportedLock struct {
+	Mode        LockMode
+	Description string
+}
+
+func (e *ErrUnsupportedLock) Is(target error) bool {
+	_, ok := target.(*ErrUnsupportedLock)
+	return ok
+}
+
+func (e *ErrUnsupportedLock) Error() string {
+	return fmt.Sprintf("%s lock is not supported, %s", e.Mode, e.Description)
+}
+
+// LockMode defines a row-level locking clause of the query.
+type LockMode string
+
+const (
+	LockForUpdate           LockMode = "FOR UPDATE"
+	LockForShare            LockMode = "FOR SHARE"
+	LockForUpdateSkipLocked LockMode = "FOR UPDATE SKIP LOCKED"
+	LockForUpdateNoWait     LockMode = "FOR UPDATE NOWAIT"
+)
+
 type join struct {
 	Relation    *Relation
 	Association Association
@@ -17,19 +43,24 @@ type join struct {
 
 type QueryMethods interface {
 	Where(cond string, arg interface{}) *Relation
+	WhereJSON(path string, arg interface{}) *Relation
 	Select(attrs ...string) *Relation
 	Group(attrs ...string) *Relation
+	Order(values ...string) *Relation
 	Joins(assocs ...string) *Relation
 	Limit(num int) *Relation
+	Lock(mode ...LockMode) *Relation
 }
 
 type QueryBuilder struct {
 	from  string
 	limit *int
+	lock  LockMode
 
 	selectValues []string
 	whereValues  []Predicate
 	groupValues  []string
+	orderValues  []string
 	joinValues   []join
 }
 
@@ -37,15 +68,18 @@ func (q *QueryBuilder) copy() *QueryBuilder {
 	newq := QueryBuilder{
 		from:         q.from,
 		limit:        q.limit,
+		lock:         q.lock,
 		selectValues: make([]string, len(q.selectValues)),
 		whereValues:  make([]Predicate, len(q.whereValues)),
 		groupValues:  make([]string, len(q.groupValues)),
+		orderValues:  make([]string, len(q.orderValues)),
 		joinValues:   make([]join, len(q.joinValues)),
 	}
 
 	copy(newq.selectValues, q.selectValues)
 	copy(newq.whereValues, q.whereValues)
 	copy(newq.groupValues, q.groupValues)
+	copy(newq.orderValues, q.orderValues)
 	copy(newq.joinValues, q.joinValues)
 
 	return &newq
@@ -67,6 +101,10 @@ func (q *QueryBuilder) Group(values ...string) {
 	q.groupValues = append(q.groupValues, values...)
 }
 
+func (q *QueryBuilder) Order(values ...string) {
+	q.orderValues = append(q.orderValues, values...)
+}
+
 func (q *QueryBuilder) Join(rel *Relation, assoc Association) {
 	q.joinValues = append(q.joinValues, join{rel, assoc})
 }
@@ -75,7 +113,20 @@ func (q *QueryBuilder) Limit(num int) {
 	q.limit = &num
 }
 
+func (q *QueryBuilder) Lock(mode LockMode) {
+	q.lock = mode
+}
+
 func (q *QueryBuilder) String() string {
+	if q.lock != "" {
+		return q.query() + " " + string(q.lock)
+	}
+	return q.query()
+}
+
+// query returns the statement without the locking clause, since not all
+// adapters support row-level locks.
+func (q *QueryBuilder) query() string {
 	if q.from == "" {
 		panic("from is not set")
 	}
@@ -106,7 +157,7 @@ func (q *QueryBuilder) String() string {
 	}
 
 	for i, where := range q.whereValues {
-		if i < len(q.whereValues)-1 {
+		if i > 0 {
 			fmt.Fprintf(&buf, ` AND`)
 		}
 		fmt.Fprintf(&buf, ` (%s)`, where.Cond)
@@ -115,6 +166,9 @@ func (q *QueryBuilder) String() string {
 	if len(q.groupValues) > 0 {
 		fmt.Fprintf(&buf, ` GROUP BY %s`, strings.Join(q.groupValues, ", "))
 	}
+	if len(q.orderValues) > 0 {
+		fmt.Fprintf(&buf, ` ORDER BY %s`, strings.Join(q.orderValues, ", "))
+	}
 	if q.limit != nil {
 		fmt.Fprintf(&buf, ` LIMIT %d`, *q.limit)
 	}
@@ -132,8 +186,9 @@ func (q *QueryBuilder) Args() []interface{} {
 
 func (q *QueryBuilder) Operation() *QueryOperation {
 	return &QueryOperation{
-		Text:    q.String(),
+		Text:    q.query(),
 		Args:    q.Args(),
 		Columns: q.selectValues,
+		Lock:    q.lock,
 	}
 }
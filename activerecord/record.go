@@ -3,7 +3,9 @@ package activerecord
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	. "github.com/activegraph/activegraph/activesupport"
 )
@@ -35,6 +37,30 @@ func (e *ErrRecordNotUnique) Error() string {
 	return e.Err.Error()
 }
 
+// ErrStaleObject is returned on attempt to update a record, which has been
+// changed by another process since it was loaded (see optimistic locking).
+type ErrStaleObject struct {
+	TableName  string
+	PrimaryKey string
+	ID         interface{}
+}
+
+func (e *ErrStaleObject) Is(target error) bool {
+	_, ok := target.(*ErrStaleObject)
+	return ok
+}
+
+func (e *ErrStaleObject) Error() string {
+	return fmt.Sprintf(
+		"attempted to update a stale object in %q by %s = %v", e.TableName, e.PrimaryKey, e.ID,
+	)
+}
+
+const (
+	// lockingColumn is a name of the column used for optimistic locking.
+	lockingColumn = "lock_version"
+)
+
 type CollectionResult struct {
 	Result[*Relation]
 }
@@ -54,17 +80,19 @@ func (c CollectionResult) ToA() (Array, error) {
 	return c.Unwrap().ToA()
 }
 
-func (c CollectionResult) DeleteAll() error {
-	records, err := c.ToA()
-	if err != nil {
-		return err
+// Scope applies the named scope to the collection.
+func (c CollectionResult) Scope(name string) CollectionResult {
+	if c.IsErr() {
+		return c
 	}
-	for i := 0; i < len(records); i++ {
-		if _, err := records[i].Delete(); err != nil {
-			return err
-		}
+	return c.Unwrap().Scope(name)
+}
+
+func (c CollectionResult) DeleteAll() error {
+	if c.IsErr() {
+		return c.Err()
 	}
-	return nil
+	return c.Unwrap().DeleteAll()
 }
 
 type RecordResult struct {
@@ -96,6 +124,12 @@ func (r RecordResult) andThen(op func(*ActiveRecord) (*ActiveRecord, error)) Rec
 	})}
 }
 
+func (r RecordResult) AssignAttributes(newAttributes map[string]interface{}) RecordResult {
+	return r.andThen(func(r *ActiveRecord) (*ActiveRecord, error) {
+		return r, r.AssignAttributes(newAttributes)
+	})
+}
+
 func (r RecordResult) Insert() RecordResult {
 	return r.andThen((*ActiveRecord).Insert)
 }
@@ -153,11 +187,16 @@ type ActiveRecord struct {
 	tableName string
 	conn      Conn
 	ctx       context.Context
+	relation  *Relation
 
 	attributes *attributes
 	AttributeMethods
 	AttributeAccessors
 
+	// nested are attributes of associated records, which are saved along
+	// with the record (see R.AcceptsNestedAttributesFor).
+	nested Hash
+
 	validations
 
 	associations *associations
@@ -181,14 +220,41 @@ func (r *ActiveRecord) init() *ActiveRecord {
 func (r *ActiveRecord) ToHash() Hash {
 	hash := make(Hash, len(r.attributes.keys))
 	for key := range r.attributes.keys {
-		hash[key] = nil
-		if value, ok := r.attributes.values[key]; ok {
-			hash[key] = value
-		}
+		hash[key] = r.Attribute(key)
 	}
 	return hash
 }
 
+// Attribute returns the value of the attribute identified by attrName, values
+// of computed attributes are computed on each access.
+func (r *ActiveRecord) Attribute(attrName string) interface{} {
+	if v, ok := r.attributes.AttributeForInspect(attrName).(VirtualAttribute); ok && v.IsComputed() {
+		return v.Compute(r)
+	}
+	return r.attributes.Attribute(attrName)
+}
+
+// AssignAttributes assigns attributes of the record, nested attributes of
+// associations are saved along with the record on Insert or Update.
+func (r *ActiveRecord) AssignAttributes(newAttributes map[string]interface{}) error {
+	attrs, nested := r.relation.splitNestedAttributes(newAttributes)
+	if err := r.attributes.AssignAttributes(attrs); err != nil {
+		return err
+	}
+
+	if len(nested) != 0 {
+		newNested := make(Hash, len(r.nested)+len(nested))
+		for name, value := range r.nested {
+			newNested[name] = value
+		}
+		for name, value := range nested {
+			newNested[name] = value
+		}
+		r.nested = newNested
+	}
+	return nil
+}
+
 func (r *ActiveRecord) Name() string {
 	return r.name
 }
@@ -199,8 +265,11 @@ func (r *ActiveRecord) Copy() *ActiveRecord {
 		tableName:    r.tableName,
 		conn:         r.conn,
 		ctx:          r.ctx,
+		relation:     r.relation,
 		attributes:   r.attributes.copy(),
+		nested:       r.nested,
 		associations: r.associations.copy(),
+		validations:  *r.validations.copy(),
 	}).init()
 }
 
@@ -244,31 +313,64 @@ func (r *ActiveRecord) Validate() error {
 	return r.validations.validate(r)
 }
 
-func (r *ActiveRecord) Insert() (*ActiveRecord, error) {
-	if err := r.Validate(); err != nil {
-		return nil, err
+// prepareInsert assigns attributes generated by the application and
+// validates the new record.
+func (r *ActiveRecord) prepareInsert() error {
+	// The locking version of a new record always starts from zero.
+	if r.HasAttribute(lockingColumn) && !r.AttributePresent(lockingColumn) {
+		if err := r.AssignAttribute(lockingColumn, int64(0)); err != nil {
+			return err
+		}
 	}
 
-	columnValues := make([]ColumnValue, 0, len(r.attributes.values))
-	for name, value := range r.attributes.values {
-		columnValue := ColumnValue{
-			Name:  name,
-			Type:  r.attributes.keys[name].AttributeType(),
-			Value: value,
+	// Identifiers of UUID type are generated by the application.
+	primaryKey := r.attributes.primaryKey.AttributeName()
+	if _, ok := underlyingType(r.attributes.primaryKey.AttributeType()).(*UUID); ok &&
+		!r.AttributePresent(primaryKey) {
+		uuid, err := NewUUID()
+		if err != nil {
+			return err
+		}
+		if err = r.AssignAttribute(primaryKey, uuid); err != nil {
+			return err
 		}
-		columnValues = append(columnValues, columnValue)
 	}
-	op := InsertOperation{
-		TableName:    r.tableName,
-		ColumnValues: columnValues,
+
+	return r.Validate()
+}
+
+func (r *ActiveRecord) Insert() (*ActiveRecord, error) {
+	if len(r.nested) != 0 {
+		return r.saveNested((*ActiveRecord).insert)
 	}
+	return r.insert()
+}
 
-	id, err := r.conn.ExecInsert(r.Context(), &op)
-	if err != nil {
+func (r *ActiveRecord) insert() (*ActiveRecord, error) {
+	if err := r.prepareInsert(); err != nil {
 		return nil, err
 	}
 
-	err = r.AssignAttribute(r.attributes.primaryKey.AttributeName(), id)
+	primaryKey := r.attributes.primaryKey.AttributeName()
+	op := InsertOperation{
+		TableName:    r.tableName,
+		ColumnValues: r.columnValues(),
+	}
+
+	err := r.transaction(func() error {
+		id, err := r.conn.ExecInsert(r.Context(), &op)
+		if err != nil {
+			return err
+		}
+
+		// The primary key is assigned by the database, when it's not set explicitly.
+		if r.Attribute(primaryKey) == nil {
+			if err = r.AssignAttribute(primaryKey, id); err != nil {
+				return err
+			}
+		}
+		return r.updateCounterCaches(1)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -276,39 +378,290 @@ func (r *ActiveRecord) Insert() (*ActiveRecord, error) {
 }
 
 func (r *ActiveRecord) Update() (*ActiveRecord, error) {
+	if len(r.nested) != 0 {
+		return r.saveNested((*ActiveRecord).update)
+	}
+	return r.update()
+}
+
+func (r *ActiveRecord) update() (*ActiveRecord, error) {
 	if err := r.Validate(); err != nil {
 		return nil, err
 	}
 
+	var rec *ActiveRecord
+	err := r.transaction(func() (err error) {
+		// Counters are moved to new targets, when the record is reassigned.
+		var prev *ActiveRecord
+		if r.relation != nil && len(r.relation.counterCaches()) != 0 && !r.IsDeleted() {
+			res := r.relation.WithContext(r.Context()).Unscoped().Find(r.ID())
+			if res.IsErr() {
+				return res.Err()
+			}
+			if prev = res.Unwrap(); prev.IsDeleted() {
+				prev = nil
+			}
+		}
+
+		if rec, err = r.updateColumns(); err != nil || prev == nil {
+			return err
+		}
+		return r.relation.reassignCounterCaches(r.Context(), prev, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *ActiveRecord) updateColumns() (*ActiveRecord, error) {
+	columnValues := r.columnValues()
+
+	// Counter caches are changed in place, so possibly stale values of the
+	// record are not written back.
+	if r.relation != nil {
+		counters := r.relation.counterCacheColumns()
+		for i := len(columnValues) - 1; i >= 0; i-- {
+			if counters[columnValues[i].Name] {
+				columnValues = append(columnValues[:i], columnValues[i+1:]...)
+			}
+		}
+	}
+
+	op := UpdateOperation{
+		TableName:    r.tableName,
+		PrimaryKey:   r.attributes.primaryKey.AttributeName(),
+		ColumnValues: columnValues,
+	}
+
+	// When the record has a locking column, the update succeeds only when the
+	// version in the database matches the version of the record, the version
+	// is incremented on each update.
+	if !r.HasAttribute(lockingColumn) {
+		return r, r.conn.ExecUpdate(r.Context(), &op)
+	}
+
+	lockVersion, err := lockingVersion(r.Attribute(lockingColumn))
+	if err != nil {
+		return nil, err
+	}
+
+	op.LockingColumn = lockingColumn
+	op.LockingValue = lockVersion
+	op.ColumnValues = make([]ColumnValue, 0, len(columnValues)+1)
+	op.ColumnValues = append(op.ColumnValues, ColumnValue{
+		Name:  lockingColumn,
+		Type:  r.attributes.keys[lockingColumn].AttributeType(),
+		Value: lockVersion + 1,
+	})
+	for _, columnValue := range columnValues {
+		if columnValue.Name != lockingColumn {
+			op.ColumnValues = append(op.ColumnValues, columnValue)
+		}
+	}
+
+	if err = r.conn.ExecUpdate(r.Context(), &op); err != nil {
+		return nil, err
+	}
+	return r, r.AssignAttribute(lockingColumn, lockVersion+1)
+}
+
+// columnValues returns values of the persisted attributes of the record.
+func (r *ActiveRecord) columnValues() []ColumnValue {
 	columnValues := make([]ColumnValue, 0, len(r.attributes.values))
 	for name, value := range r.attributes.values {
+		attr := r.attributes.keys[name]
+		if isVirtual(attr) {
+			continue
+		}
 		columnValue := ColumnValue{
 			Name:  name,
-			Type:  r.attributes.keys[name].AttributeType(),
+			Type:  attr.AttributeType(),
 			Value: value,
 		}
 		columnValues = append(columnValues, columnValue)
 	}
 
-	op := UpdateOperation{
-		TableName:    r.tableName,
-		PrimaryKey:   r.attributes.primaryKey.AttributeName(),
-		ColumnValues: columnValues,
+	// Columns are sorted, so statements of records with the same attributes
+	// are the same.
+	sort.Slice(columnValues, func(i, j int) bool {
+		return columnValues[i].Name < columnValues[j].Name
+	})
+	return columnValues
+}
+
+// WithLock starts a transaction, reloads the record with FOR UPDATE lock and
+// calls the given function with the locked record. When the function returns
+// an error, the transaction is rolled back. Otherwise the transaction is committed
+// and the attributes of the record are replaced with the locked record ones.
+//
+//	err := book.WithLock(ctx, func(book *activerecord.ActiveRecord) error {
+//		quantity := book.Attribute("quantity").(int64)
+//		if err := book.AssignAttribute("quantity", quantity-1); err != nil {
+//			return err
+//		}
+//		_, err := book.Update()
+//		return err
+//	})
+//
+// Adapters without row-level locks (e.g. SQLite) lock the whole database for
+// writing at the beginning of the transaction.
+func (r *ActiveRecord) WithLock(ctx context.Context, fn func(*ActiveRecord) error) error {
+	if r.relation == nil {
+		return fmt.Errorf("%s could not be locked without relation", r.name)
 	}
 
-	return r, r.conn.ExecUpdate(r.Context(), &op)
+	var (
+		rel    = r.relation.WithContext(ctx)
+		locked *ActiveRecord
+	)
+
+	ctx = ContextWithLock(ctx, LockForUpdate)
+	err := rel.connections.Transaction(ctx, rel.connectionName, func() error {
+		rec := rel.Lock().Find(r.ID())
+		if rec.IsErr() {
+			return rec.Err()
+		}
+		locked = rec.Unwrap()
+		return fn(locked)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.attributes = locked.attributes.copy()
+	r.init()
+	return nil
+}
+
+// lockingVersion returns the version of the record for optimistic locking.
+func lockingVersion(value interface{}) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	version, err := new(Int64).Deserialize(value)
+	if err != nil {
+		return 0, err
+	}
+	return version.(int64), nil
 }
 
+// Delete deletes the record from the database. Records of models with soft
+// delete are marked as deleted instead (see R.SoftDelete).
 func (r *ActiveRecord) Delete() (*ActiveRecord, error) {
+	column := r.softDeleteColumn()
+	if column == "" {
+		return r.HardDelete()
+	}
+
+	deleted := r.IsDeleted()
+	err := r.transaction(func() error {
+		if _, err := r.updateColumn(column, time.Now().UTC()); err != nil || deleted {
+			return err
+		}
+		return r.updateCounterCaches(-1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// HardDelete deletes the record from the database permanently, even when
+// the model deletes records softly.
+func (r *ActiveRecord) HardDelete() (*ActiveRecord, error) {
 	op := DeleteOperation{
 		TableName:  r.tableName,
 		PrimaryKey: r.attributes.primaryKey.AttributeName(),
 		Value:      r.ID(),
 	}
 
-	err := r.conn.ExecDelete(r.Context(), &op)
+	err := r.transaction(func() error {
+		// Softly deleted records are not counted already.
+		if err := r.conn.ExecDelete(r.Context(), &op); err != nil || r.IsDeleted() {
+			return err
+		}
+		return r.updateCounterCaches(-1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Restore restores the softly deleted record.
+func (r *ActiveRecord) Restore() (*ActiveRecord, error) {
+	column := r.softDeleteColumn()
+	if column == "" {
+		return nil, fmt.Errorf("%s does not support soft delete", r.name)
+	}
+
+	deleted := r.IsDeleted()
+	err := r.transaction(func() error {
+		if _, err := r.updateColumn(column, nil); err != nil || !deleted {
+			return err
+		}
+		return r.updateCounterCaches(1)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
+
+// IsDeleted returns true, when the record is softly deleted.
+func (r *ActiveRecord) IsDeleted() bool {
+	column := r.softDeleteColumn()
+	return column != "" && r.AttributePresent(column)
+}
+
+func (r *ActiveRecord) softDeleteColumn() string {
+	if r.relation == nil {
+		return ""
+	}
+	return r.relation.softDelete
+}
+
+// transaction runs the given function within a transaction, when the write of
+// the record changes counter caches of targets, so the record and counters are
+// written atomically. The record writes through the connection of the transaction.
+func (r *ActiveRecord) transaction(fn func() error) error {
+	if r.relation == nil || len(r.relation.counterCaches()) == 0 {
+		return fn()
+	}
+
+	rel := r.relation.WithContext(r.Context())
+	conn := r.conn
+	defer func() { r.conn = conn }()
+
+	return rel.connections.Transaction(r.Context(), rel.connectionName, func() error {
+		r.conn = rel.Connection()
+		return fn()
+	})
+}
+
+// updateCounterCaches changes counters of targets referenced by the record.
+func (r *ActiveRecord) updateCounterCaches(delta int) error {
+	if r.relation == nil {
+		return nil
+	}
+	return r.relation.updateCounterCaches(r.Context(), []*ActiveRecord{r}, delta)
+}
+
+// updateColumn updates a single column of the record skipping validations.
+func (r *ActiveRecord) updateColumn(name string, value interface{}) (*ActiveRecord, error) {
+	primaryKey := r.attributes.primaryKey
+	op := UpdateOperation{
+		TableName:  r.tableName,
+		PrimaryKey: primaryKey.AttributeName(),
+		ColumnValues: []ColumnValue{
+			{Name: primaryKey.AttributeName(), Type: primaryKey.AttributeType(), Value: r.ID()},
+			{Name: name, Type: r.attributes.keys[name].AttributeType(), Value: value},
+		},
+	}
+
+	if err := r.conn.ExecUpdate(r.Context(), &op); err != nil {
+		return nil, err
+	}
+	return r, r.AssignAttribute(name, value)
+}
@@ -1,8 +1,13 @@
 package activerecord_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -159,3 +164,529 @@ func TestActiveRecord_HasOne_AccessAssociation(t *testing.T) {
 	account := suppliers[0].Association("account").Unwrap()
 	require.Equal(t, accounts[0].ID(), account.ID())
 }
+
+func TestActiveRecord_Update_OptimisticLocking(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.Int64("lock_version")
+		})
+	})
+
+	Book := activerecord.New("book")
+
+	book := Book.Create(Hash{"title": "Solaris"}).Unwrap()
+	require.Equal(t, int64(0), book.Attribute("lock_version"))
+
+	book1 := Book.Find(book.ID()).Unwrap()
+	book2 := Book.Find(book.ID()).Unwrap()
+
+	require.NoError(t, book1.AssignAttribute("title", "Solaris (1961)"))
+	_, err = book1.Update()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), book1.Attribute("lock_version"))
+
+	// The second copy of the book is stale now, so the update must fail.
+	require.NoError(t, book2.AssignAttribute("title", "Solaris (1972)"))
+	_, err = book2.Update()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, new(activerecord.ErrStaleObject)))
+	require.Equal(t, int64(0), book2.Attribute("lock_version"))
+
+	book = Book.Find(book.ID()).Unwrap()
+	require.Equal(t, "Solaris (1961)", book.Attribute("title"))
+	require.Equal(t, int64(1), book.Attribute("lock_version"))
+
+	// Rows without version are updated as rows of the initial version.
+	require.NoError(t, Book.UpdateAll(Hash{"lock_version": nil}))
+	book = Book.Find(book.ID()).Unwrap()
+	require.Nil(t, book.Attribute("lock_version"))
+
+	require.NoError(t, book.AssignAttribute("title", "Solaris (2002)"))
+	_, err = book.Update()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), book.Attribute("lock_version"))
+
+	// The locking column added with the helper always has a version.
+	activerecord.Migrate(t.Name()+"_lock_version", func(m *activerecord.M) {
+		m.CreateTable("magazines", func(t *activerecord.Table) {
+			t.String("title")
+			t.LockVersion()
+		})
+	})
+
+	conn, err := activerecord.RetrieveConnection("primary")
+	require.NoError(t, err)
+	columns, err := conn.ColumnDefinitions(context.TODO(), "magazines")
+	require.NoError(t, err)
+	for _, column := range columns {
+		if column.Name == "lock_version" {
+			require.True(t, column.NotNull)
+			require.Equal(t, "0", fmt.Sprint(column.Default))
+		}
+	}
+}
+
+func TestActiveRecord_WithLock(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:     "sqlite3",
+		Database:    t.Name() + ".db",
+		BusyTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.Int64("quantity")
+		})
+	})
+
+	Book := activerecord.New("book")
+	book := Book.Create(Hash{"title": "Dune", "quantity": 10}).Unwrap()
+
+	decrement := func(book *activerecord.ActiveRecord) error {
+		quantity := book.Attribute("quantity").(int64)
+		if err := book.AssignAttribute("quantity", quantity-1); err != nil {
+			return err
+		}
+		_, err := book.Update()
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 5)
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			book := Book.Find(book.ID()).Unwrap()
+			errs <- book.WithLock(context.TODO(), decrement)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// The record is reloaded after the lock is released.
+	require.NoError(t, book.WithLock(context.TODO(), decrement))
+	require.Equal(t, int64(4), book.Attribute("quantity"))
+
+	// Changes are rolled back on error.
+	err = book.WithLock(context.TODO(), func(book *activerecord.ActiveRecord) error {
+		if err := decrement(book); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	book = Book.Find(book.ID()).Unwrap()
+	require.Equal(t, int64(4), book.Attribute("quantity"))
+
+	// Records without relation could not be reloaded with the lock.
+	err = new(activerecord.ActiveRecord).WithLock(context.TODO(), decrement)
+	require.Error(t, err)
+}
+
+func TestActiveRecord_Types(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("products", func(t *activerecord.Table) {
+			t.UUID("id")
+			t.PrimaryKey("id")
+			t.Decimal("price", activerecord.Precision(20), activerecord.Scale(2))
+			t.Binary("thumbnail")
+			t.Enum("status", []string{"draft", "published"}, activerecord.Default("draft"))
+		})
+	})
+
+	Product := activerecord.New("product")
+
+	price, err := ParseBigDecimal("123456789012345678.99")
+	require.NoError(t, err)
+	thumbnail := []byte{0x89, 'P', 'N', 'G', 0x00, '\'', 0xff}
+
+	product := Product.Create(Hash{"price": price, "thumbnail": thumbnail}).Unwrap()
+
+	// Identifier is generated on insert.
+	id, ok := product.ID().(string)
+	require.True(t, ok)
+	require.Len(t, id, 36)
+	require.Equal(t, byte('4'), id[14])
+
+	product = Product.Find(id).Unwrap()
+	require.Equal(t, id, product.ID())
+	require.Equal(t, "draft", product.Attribute("status"))
+	require.Equal(t, thumbnail, product.Attribute("thumbnail"))
+	require.Equal(t, "123456789012345678.99", product.Attribute("price").(BigDecimal).String())
+
+	// Decimal columns keep the type, precision and scale.
+	conn, err := activerecord.RetrieveConnection("primary")
+	require.NoError(t, err)
+	columns, err := conn.ColumnDefinitions(context.TODO(), "products")
+	require.NoError(t, err)
+	for _, column := range columns {
+		if column.Name == "price" {
+			require.IsType(t, new(activerecord.Decimal), column.Type)
+			require.Equal(t, []int{20, 2}, []int{column.Precision, column.Scale})
+		}
+	}
+
+	// Binary data is updated along with the rest of attributes.
+	require.NoError(t, product.AssignAttributes(Hash{
+		"thumbnail": []byte{0x00, 0x01}, "status": "published",
+	}))
+	_, err = product.Update()
+	require.NoError(t, err)
+
+	product = Product.Find(id).Unwrap()
+	require.Equal(t, []byte{0x00, 0x01}, product.Attribute("thumbnail"))
+	require.Equal(t, "published", product.Attribute("status"))
+
+	// Enum values are restricted by the check constraint.
+	require.Error(t, Product.Create(Hash{"status": "archived"}).Err())
+
+	// Explicitly set identifiers are preserved.
+	const explicitID = "1b4e28ba-2fa1-41d2-883f-0016d3cca427"
+	product = Product.Create(Hash{"id": explicitID}).Unwrap()
+	require.Equal(t, explicitID, product.ID())
+}
+
+func TestActiveRecord_DecimalOrder(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("products", func(t *activerecord.Table) {
+			t.Decimal("price", activerecord.Precision(20), activerecord.Scale(2))
+		})
+	})
+
+	Product := activerecord.New("product")
+
+	for _, s := range []string{"10.00", "9.50", "-2.5", "100.25", "123456789012345678.99"} {
+		price, err := ParseBigDecimal(s)
+		require.NoError(t, err)
+		Product.Create(Hash{"price": price}).Unwrap()
+	}
+
+	prices := func(rel *activerecord.Relation) []string {
+		products, err := rel.ToA()
+		require.NoError(t, err)
+
+		var prices []string
+		for _, product := range products {
+			prices = append(prices, product.Attribute("price").(BigDecimal).String())
+		}
+		return prices
+	}
+
+	// Decimals are compared as numbers, not as strings.
+	require.Equal(t,
+		[]string{"-2.5", "9.50", "10.00", "100.25", "123456789012345678.99"},
+		prices(Product.Order("price")),
+	)
+
+	low, err := ParseBigDecimal("9.5")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"10.00", "100.25"},
+		prices(Product.Where("price > ?", low).Where("price <= ?", "100.250").Order("price")),
+	)
+	require.Equal(t, []string{"9.50"}, prices(Product.Where("price", "9.5")))
+}
+
+func TestActiveRecord_Enum(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.Int64("status", activerecord.NotNull(), activerecord.Default(0))
+			t.String("format")
+		})
+	})
+
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.Enum("status", "draft", "published", "archived")
+		r.Enum("format", "paperback", "hardcover")
+	})
+
+	status := Book.AttributeForInspect("status").AttributeType()
+	require.Equal(t, &activerecord.Enum{
+		Values: []string{"draft", "published", "archived"}, Integer: true,
+	}, status)
+
+	Book.Create(Hash{"title": "Dune", "status": "published"}).Unwrap()
+	Book.Create(Hash{"title": "Emma", "format": "hardcover"}).Unwrap()
+
+	// Unknown values are rejected by validations.
+	err = Book.Create(Hash{"title": "Ulysses", "status": "lost"}).Err()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not included in the list")
+
+	books, err := Book.Where("status", "published").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Dune", books[0].Attribute("title"))
+
+	books, err = Book.Where("status", "draft").Where("format", "hardcover").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Emma", books[0].Attribute("title"))
+
+	book := Book.Find(books[0].ID()).Unwrap()
+	require.Equal(t, "draft", book.Attribute("status"))
+	require.Equal(t, "hardcover", book.Attribute("format"))
+
+	// Values must be valid names of GraphQL enum values.
+	for _, value := range []string{"in-progress", "1st", "true", "null", "draft"} {
+		require.Panics(t, func() {
+			activerecord.New("book", func(r *activerecord.R) {
+				r.Enum("status", "draft", value)
+			})
+		}, value)
+	}
+}
+
+func TestActiveRecord_VirtualAttributes(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("first_name")
+			t.String("last_name")
+			t.String("status")
+			t.String("password_digest")
+		})
+	})
+
+	User := activerecord.New("user", func(r *activerecord.R) {
+		r.Default("status", "active")
+		r.Attribute("password", new(activerecord.String))
+		r.Attribute("full_name", new(activerecord.String), activerecord.Virtual(
+			func(u *activerecord.ActiveRecord) interface{} {
+				return fmt.Sprintf("%s %s", u.Attribute("first_name"), u.Attribute("last_name"))
+			},
+		))
+	})
+
+	user := User.Create(Hash{
+		"first_name": "Jules", "last_name": "Verne", "password": "secret",
+	}).Unwrap()
+	require.Equal(t, "active", user.Attribute("status"))
+	require.Equal(t, "Jules Verne", user.Attribute("full_name"))
+	require.Equal(t, "Jules Verne", user.ToHash()["full_name"])
+
+	// Computed attributes are read-only.
+	err = user.AssignAttribute("full_name", "Victor Hugo")
+	require.IsType(t, new(activerecord.ErrReadOnlyAttribute), err)
+
+	// Virtual attributes are not persisted.
+	user = User.Find(user.ID()).Unwrap()
+	require.Nil(t, user.Attribute("password"))
+	require.Equal(t, "Jules Verne", user.Attribute("full_name"))
+
+	require.NoError(t, user.AssignAttributes(Hash{"first_name": "Jule", "password": "other"}))
+	_, err = user.Update()
+	require.NoError(t, err)
+	require.Equal(t, "Jule Verne", User.Find(user.ID()).Unwrap().Attribute("full_name"))
+}
+
+func TestActiveRecord_Store(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("name")
+			t.JSON("settings")
+		})
+	})
+
+	// Stores are JSON attributes only.
+	for _, store := range []string{"name", "preferences"} {
+		_, err = activerecord.Initialize("user", func(r *activerecord.R) {
+			r.Store(store, "theme")
+		})
+		require.Error(t, err, store)
+	}
+	require.Panics(t, func() {
+		activerecord.New("user", func(r *activerecord.R) {
+			r.DefineStoreAttribute("settings", "visits", new(activerecord.Int64))
+		})
+	})
+
+	User := activerecord.New("user", func(r *activerecord.R) {
+		r.Store("settings", "theme", "locale")
+		r.DefineStoreAttribute("settings", "newsletter", new(activerecord.Boolean))
+	})
+	require.IsType(t, activerecord.Nil{}, User.AttributeForInspect("settings").AttributeType())
+
+	user := User.Create(Hash{
+		"name": "Jules", "settings": Hash{"notifications": Hash{"email": true}}, "theme": "dark",
+	}).Unwrap()
+	require.Equal(t, "dark", user.Attribute("theme"))
+	require.Nil(t, user.Attribute("locale"))
+
+	User.Create(Hash{"name": "Victor", "locale": "fr"}).Unwrap()
+
+	user = User.Find(user.ID()).Unwrap()
+	require.Equal(t, Hash{
+		"theme": "dark", "notifications": map[string]interface{}{"email": true},
+	}, user.Attribute("settings"))
+
+	require.NoError(t, user.AssignAttribute("locale", "en"))
+	_, err = user.Update()
+	require.NoError(t, err)
+	require.Equal(t, "en", User.Find(user.ID()).Unwrap().Attribute("locale"))
+
+	// Values of keys are typed.
+	require.Error(t, User.Create(Hash{"name": "Paul", "theme": 1}).Err())
+	require.Error(t, user.AssignAttribute("newsletter", "yes"))
+	require.NoError(t, user.AssignAttribute("newsletter", true))
+	require.Equal(t, true, user.Attribute("newsletter"))
+
+	require.NoError(t, user.AssignAttribute("settings", Hash{"theme": false}))
+	require.Nil(t, user.Attribute("theme"))
+}
+
+func TestActiveRecord_NestedAttributes(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name()+"_create", func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+		})
+		m.CreateTable("books", func(t *activerecord.Table) {
+			t.String("title")
+			t.References("authors")
+		})
+	})
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.HasMany("books")
+		r.AcceptsNestedAttributesFor("books", activerecord.AllowDestroy())
+	})
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.BelongsTo("author")
+		r.ValidatesPresence("title")
+	})
+
+	author := Author.Create(Hash{
+		"name":  "Herman Melville",
+		"books": []interface{}{Hash{"title": "Moby Dick"}, Hash{"title": "Omoo"}},
+	})
+	require.NoError(t, author.Err())
+
+	books, err := author.Collection("books").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+
+	// Validation errors of associated records are addressed by nested paths,
+	// nothing is saved on errors.
+	invalid := Author.Create(Hash{
+		"name":  "Noah Harari",
+		"books": []interface{}{Hash{"title": "Sapiens"}, Hash{"title": ""}},
+	})
+	require.Error(t, invalid.Err())
+	require.IsType(t, activerecord.ErrValidation{}, invalid.Err())
+	require.Contains(t, invalid.Err().Error(), "'books[1].title' can't be blank")
+
+	authors, err := Author.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+
+	// Records with identifiers are updated or deleted, other are created.
+	err = author.Unwrap().AssignAttributes(Hash{
+		"books": []map[string]interface{}{
+			{"id": books[0].ID(), "title": "Moby-Dick"},
+			{"id": books[1].ID(), activerecord.DestroyAttributeName: true},
+			{"title": "Typee"},
+		},
+	})
+	require.NoError(t, err)
+	_, err = author.Unwrap().Update()
+	require.NoError(t, err)
+
+	books, err = author.Collection("books").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+	require.Equal(t, "Moby-Dick", books[0].Attribute("title"))
+	require.Equal(t, "Typee", books[1].Attribute("title"))
+
+	// Records of other owners cannot be updated.
+	other := Author.Create(Hash{"name": "Noah Harari"}).Unwrap()
+	err = other.AssignAttributes(Hash{
+		"books": []interface{}{Hash{"id": books[0].ID(), "title": "Sapiens"}},
+	})
+	require.NoError(t, err)
+	_, err = other.Update()
+	require.True(t, errors.Is(err, new(activerecord.ErrRecordNotFound)))
+	require.Equal(t, "Moby-Dick", Book.Find(books[0].ID()).Unwrap().Attribute("title"))
+
+	_, err = activerecord.Initialize("publisher", func(r *activerecord.R) {
+		r.TableName("authors")
+		r.AcceptsNestedAttributesFor("books")
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package activerecord
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+var (
+	defaultRegistry = &Registry{
+		connections: globalConnectionHandler,
+		reflection:  globalReflection,
+	}
+)
+
+// Registry keeps the state of established database connections and models
+// reflection. Package-level functions use the default registry, create a new
+// registry to run multiple isolated applications within a single process.
+//
+//	registry := activerecord.NewRegistry()
+//	registry.EstablishConnection(activerecord.DatabaseConfig{
+//		Adapter: "sqlite3", Database: "main.db",
+//	})
+//
+//	Author := registry.New("author")
+type Registry struct {
+	connections *connectionHandler
+	reflection  *Reflection
+
+	// migrations is a list of migrations in the order of definition.
+	migrations []*M
+	mu         sync.Mutex
+}
+
+// NewRegistry returns a new empty registry. Connection adapters are shared
+// between all registries.
+func NewRegistry() *Registry {
+	return &Registry{
+		connections: newConnectionHandler(globalConnectionHandler.adapters),
+		reflection:  NewReflection(),
+	}
+}
+
+// DefaultRegistry returns the registry used by package-level functions.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Reflection returns reflection of models defined within the registry.
+func (reg *Registry) Reflection() *Reflection {
+	return reg.reflection
+}
+
+// EstablishConnection establishes connection to the database within the registry.
+func (reg *Registry) EstablishConnection(c DatabaseConfig) (Conn, error) {
+	return reg.connections.EstablishConnection(c)
+}
+
+func (reg *Registry) RetrieveConnection(name string) (Conn, error) {
+	return reg.connections.RetrieveConnection(name)
+}
+
+func (reg *Registry) RemoveConnection(name string) error {
+	return reg.connections.RemoveConnection(name)
+}
+
+// Ping verifies that all connections established within the registry are alive.
+func (reg *Registry) Ping(ctx context.Context) error {
+	return reg.connections.Ping(ctx)
+}
+
+// PoolStats returns statistics of connection pools established within the registry.
+func (reg *Registry) PoolStats() map[string]sql.DBStats {
+	return reg.connections.PoolStats()
+}
+
+// Transaction runs the given block in a transaction of the primary connection.
+func (reg *Registry) Transaction(ctx context.Context, fn func() error) error {
+	return reg.connections.Transaction(ctx, primaryConnectionName, fn)
+}
+
+// TransactionOn runs the given block in a transaction of the named connection.
+func (reg *Registry) TransactionOn(ctx context.Context, name string, fn func() error) error {
+	return reg.connections.Transaction(ctx, name, fn)
+}
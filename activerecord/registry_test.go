@@ -0,0 +1,63 @@
+package activerecord_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	_ "github.com/activegraph/activegraph/activerecord/sqlite3"
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+func TestRegistry_Isolation(t *testing.T) {
+	registry1 := activerecord.NewRegistry()
+	registry2 := activerecord.NewRegistry()
+
+	_, err := registry1.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + "_1.db",
+	})
+	require.NoError(t, err)
+	_, err = registry2.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter: "sqlite3", Database: t.Name() + "_2.db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + "_1.db")
+	defer os.Remove(t.Name() + "_2.db")
+	defer registry1.RemoveConnection("primary")
+	defer registry2.RemoveConnection("primary")
+
+	// The default registry does not know about connections of other registries.
+	_, err = activerecord.RetrieveConnection("primary")
+	require.Error(t, err)
+
+	registry1.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+		})
+	})
+	registry2.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("authors", func(t *activerecord.Table) {
+			t.String("name")
+			t.DateTime("born_at")
+		})
+	})
+
+	Author1 := registry1.New("author")
+	Author2 := registry2.New("author")
+
+	require.Equal(t, []string{"id", "name"}, Author1.AttributeNames())
+	require.Equal(t, []string{"born_at", "id", "name"}, Author2.AttributeNames())
+
+	author1, err := registry1.Reflection().Reflection("author")
+	require.NoError(t, err)
+	require.Equal(t, Author1, author1)
+
+	Author1.Create(Hash{"name": "Philip Dick"}).Expect("author was not created")
+
+	authors, err := Author2.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 0)
+}
@@ -3,7 +3,11 @@ package activerecord
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	. "github.com/activegraph/activegraph/activesupport"
 )
@@ -17,16 +21,42 @@ func (e *ErrUnknownPrimaryKey) Error() string {
 	return fmt.Sprintf("Primary key is unknown, %s", e.Description)
 }
 
+// ErrUnknownScope is returned on attempt to apply the scope, which is not
+// defined for the model.
+type ErrUnknownScope struct {
+	RecordName string
+	Scope      string
+}
+
+func (e *ErrUnknownScope) Is(target error) bool {
+	_, ok := target.(*ErrUnknownScope)
+	return ok
+}
+
+func (e *ErrUnknownScope) Error() string {
+	return fmt.Sprintf("scope %q is not defined for %s", e.Scope, e.RecordName)
+}
+
 type R struct {
 	rel *Relation
 
-	tableName   string
-	primaryKey  string
-	attrs       attributesMap
-	assocs      associationsMap
-	validators  validatorsMap
-	reflection  *Reflection
-	connections *connectionHandler
+	tableName  string
+	primaryKey string
+	attrs      attributesMap
+	assocs     associationsMap
+	validators validatorsMap
+	defaults   Hash
+	enums      map[string][]string
+	encrypts   map[string]*Encrypted
+	softDelete string
+	scopes     map[string]func(*Relation) *Relation
+	nested     map[string]NestedAttributes
+	reflection *Reflection
+
+	defaultScopes []func(*Relation) *Relation
+	connections   *connectionHandler
+
+	connectionName string
 }
 
 // TableName sets the table name explicitly.
@@ -42,12 +72,193 @@ func (r *R) PrimaryKey(name string) {
 	r.primaryKey = name
 }
 
+// ConnectsTo sets the name of the database connection used by the model. By
+// default all models use the "primary" connection.
+//
+//	Report := activerecord.New("report", func(r *activerecord.R) {
+//		r.ConnectsTo("analytics")
+//	})
+func (r *R) ConnectsTo(name string) {
+	r.connectionName = name
+}
+
 func (r *R) DefineAttribute(name string, t Type, validators ...AttributeValidator) {
 	r.attrs[name] = attr{Name: name, Type: t}
 	r.validators.include(name, typeValidator{t})
 	r.validators.include(name, validators...)
 }
 
+// Enum defines the attribute restricted to the list of values. The attribute
+// could be backed by either a string or an integer column, in the latter case
+// values are stored as indexes of the list.
+//
+//	Book := activerecord.New("book", func(r *activerecord.R) {
+//		r.Enum("status", "draft", "published", "archived")
+//	})
+//
+//	published := Book.Where("status", "published")
+//
+// Assignment of values out of the list fails the record validation.
+//
+// Values are used as names of enum values in the GraphQL schema, therefore
+// each value must start with a letter or underscore followed by letters,
+// digits or underscores (e.g. "in_progress" instead of "in-progress"), and
+// must not be "true", "false" or "null". Method panics on invalid values.
+func (r *R) Enum(name string, values ...string) {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if !IsEnumValueName(value) {
+			panic(fmt.Sprintf("activerecord: enum %q has invalid value %q", name, value))
+		}
+		if seen[value] {
+			panic(fmt.Sprintf("activerecord: enum %q has duplicate value %q", name, value))
+		}
+		seen[value] = true
+	}
+
+	r.enums[name] = values
+	r.validators.include(name, &Inclusion{In: Strings(values...), AllowNil: true})
+}
+
+var enumValueRegexp = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// IsEnumValueName returns true, when the value could be used as a name of
+// the GraphQL enum value.
+func IsEnumValueName(value string) bool {
+	switch value {
+	case "true", "false", "null":
+		return false
+	}
+	return enumValueRegexp.MatchString(value)
+}
+
+// Attribute defines the attribute, which is not persisted in the database,
+// e.g. a password that is hashed before saving into another column. Computed
+// read-only attributes are defined using Virtual option.
+//
+//	User := activerecord.New("user", func(r *activerecord.R) {
+//		r.Attribute("password", new(activerecord.String))
+//	})
+func (r *R) Attribute(name string, t Type, opts ...AttributeOption) {
+	v := VirtualAttribute{Attribute: attr{Name: name, Type: t}}
+	for _, opt := range opts {
+		opt(&v)
+	}
+
+	r.attrs[name] = v
+	if !v.IsComputed() {
+		r.validators.include(name, typeValidator{t})
+	}
+}
+
+// Encrypts defines the attribute encrypted with keys set by ConfigureEncryption.
+// The attribute must be backed by a string column.
+//
+//	User := activerecord.New("user", func(r *activerecord.R) {
+//		r.Encrypts("ssn", activerecord.Deterministic())
+//	})
+//
+//	users := User.Where("ssn", "123-45-6789")
+func (r *R) Encrypts(name string, options ...EncryptionOption) {
+	encrypted := new(Encrypted)
+	for _, option := range options {
+		option(encrypted)
+	}
+	r.encrypts[name] = encrypted
+}
+
+// SoftDelete makes records of the model deleted softly: Delete assigns the
+// current time to the column instead of deleting the row. Deleted records are
+// excluded from queries, unless WithDeleted or OnlyDeleted is used.
+//
+//	Book := activerecord.New("book", func(r *activerecord.R) {
+//		r.SoftDelete("deleted_at")
+//	})
+func (r *R) SoftDelete(columnName string) {
+	r.softDelete = columnName
+}
+
+// Scope defines the named scope of the model, the scope is applied to the
+// relation by calling Relation.Scope.
+//
+//	Book := activerecord.New("book", func(r *activerecord.R) {
+//		r.Scope("published", func(rel *activerecord.Relation) *activerecord.Relation {
+//			return rel.Where("status", "published")
+//		})
+//	})
+//
+//	books, err := Book.Scope("published").ToA()
+//
+// Method panics, when the scope with the same name is already defined.
+func (r *R) Scope(name string, fn func(*Relation) *Relation) {
+	if _, dup := r.scopes[name]; dup {
+		panic(fmt.Sprintf("activerecord: scope %q is already defined", name))
+	}
+	r.scopes[name] = fn
+}
+
+// DefaultScope defines the scope applied to every query of the model, unless
+// the relation is unscoped (see Relation.Unscoped). Only conditions of default
+// scopes are applied.
+//
+//	Book := activerecord.New("book", func(r *activerecord.R) {
+//		r.DefaultScope(func(rel *activerecord.Relation) *activerecord.Relation {
+//			return rel.Where("year > ?", 1800)
+//		})
+//	})
+func (r *R) DefaultScope(fn func(*Relation) *Relation) {
+	r.defaultScopes = append(r.defaultScopes, fn)
+}
+
+// Store defines accessors of the keys within the JSON attribute, the keys are
+// read and written as regular attributes of string type.
+//
+//	User := activerecord.New("user", func(r *activerecord.R) {
+//		r.Store("settings", "theme", "locale")
+//	})
+//
+//	user := User.New(Hash{"theme": "dark"}).Unwrap()
+//	user.Attribute("settings") // Hash{"theme": "dark"}
+//
+// The store must be an attribute of JSON type.
+func (r *R) Store(store string, keys ...string) {
+	for _, key := range keys {
+		r.DefineStoreAttribute(store, key, new(String))
+	}
+}
+
+// DefineStoreAttribute defines the accessor of the key within the JSON
+// attribute with values of the given type. Only types of JSON values are
+// supported: String, Float64 and Boolean, method panics on other types.
+//
+//	User := activerecord.New("user", func(r *activerecord.R) {
+//		r.DefineStoreAttribute("settings", "notifications", new(activerecord.Boolean))
+//	})
+//
+// Assignment of values of other types fails, values of other types written
+// to the store directly are read as nil.
+func (r *R) DefineStoreAttribute(store, name string, t Type) {
+	switch underlyingType(t).(type) {
+	case *String, *Float64, *Boolean:
+	default:
+		panic(fmt.Sprintf("activerecord: type %s of store attribute %q is not supported", t, name))
+	}
+	if _, ok := t.(Nil); !ok {
+		t = Nil{t}
+	}
+	r.attrs[name] = StoreAttribute{Attribute: attr{Name: name, Type: t}, Store: store}
+}
+
+// Default sets the default value of the attribute assigned to new records.
+// Explicitly set defaults take precedence over defaults of table columns.
+//
+//	Book := activerecord.New("book", func(r *activerecord.R) {
+//		r.Default("status", "draft")
+//	})
+func (r *R) Default(name string, value interface{}) {
+	r.defaults[name] = value
+}
+
 func (r *R) Validates(name string, validator AttributeValidator) {
 	if v, ok := validator.(Initializer); ok {
 		err := v.Initialize()
@@ -93,8 +304,30 @@ func (r *R) HasOne(name string) {
 	r.assocs[name] = &HasOne{targetName: name, owner: r.rel, reflection: r.reflection}
 }
 
+// AcceptsNestedAttributesFor allows to create, update and delete records of
+// the has-many association through attributes of the owner. Records with
+// identifiers are updated, records without identifiers are created. The owner
+// and associated records are saved within a single transaction.
+//
+//	Author := activerecord.New("author", func(r *activerecord.R) {
+//		r.HasMany("books")
+//		r.AcceptsNestedAttributesFor("books", activerecord.AllowDestroy())
+//	})
+//
+//	author := Author.Create(Hash{
+//		"name":  "Herman Melville",
+//		"books": []Hash{{"title": "Moby Dick"}, {"title": "Omoo"}},
+//	})
+func (r *R) AcceptsNestedAttributesFor(assocName string, options ...NestedAttributesOption) {
+	nested := NestedAttributes{AssociationName: assocName}
+	for _, option := range options {
+		option(&nested)
+	}
+	r.nested[assocName] = nested
+}
+
 func (r *R) init(ctx context.Context, tableName string) error {
-	conn, err := r.connections.RetrieveConnection(primaryConnectionName)
+	conn, err := r.connections.RetrieveConnection(r.connectionName)
 	if err != nil {
 		return err
 	}
@@ -105,31 +338,107 @@ func (r *R) init(ctx context.Context, tableName string) error {
 	}
 
 	for _, column := range definitions {
-		columnType := column.Type
-		if !column.NotNull {
-			columnType = Nil{columnType}
+		// Attributes explicitly defined by the user take precedence over
+		// the attributes derived from the table schema.
+		if _, ok := r.attrs[column.Name]; !ok {
+			columnType := column.Type
+			if values, ok := r.enums[column.Name]; ok {
+				_, isInteger := underlyingType(column.Type).(*Int64)
+				columnType = &Enum{Values: values, Integer: isInteger}
+			}
+			if encrypted, ok := r.encrypts[column.Name]; ok {
+				columnType = encrypted.bind(tableName, column.Name)
+			}
+			if !column.NotNull {
+				columnType = Nil{columnType}
+			}
+			if _, ok := r.enums[column.Name]; ok {
+				// Enum values are validated by the inclusion validator.
+				r.attrs[column.Name] = attr{Name: column.Name, Type: columnType}
+			} else {
+				r.DefineAttribute(column.Name, columnType)
+			}
+
+			// Values of NOT NULL columns must be present, unless the database
+			// assigns the default value. Presence treats true as a blank value,
+			// so boolean columns are omitted.
+			_, isBoolean := column.Type.(*Boolean)
+			if column.NotNull && !column.IsPrimaryKey && !isBoolean &&
+				column.Default == nil && column.DefaultSQL == "" {
+				r.validators.include(column.Name, new(Presence))
+			}
+		}
+
+		// Default values are assigned to new records on initialization.
+		if _, ok := r.defaults[column.Name]; !ok && column.Default != nil {
+			r.defaults[column.Name] = column.Default
+
+			// Enum defaults are stored as indexes of integer columns.
+			if _, ok := r.enums[column.Name]; ok {
+				value, err := r.attrs[column.Name].AttributeType().Deserialize(column.Default)
+				if err != nil {
+					return err
+				}
+				r.defaults[column.Name] = value
+			}
 		}
-		r.DefineAttribute(column.Name, columnType)
 
-		if column.IsPrimaryKey {
+		if column.IsPrimaryKey && r.primaryKey == "" {
 			r.PrimaryKey(column.Name)
 		}
 	}
+
+	// Keys of stores are read and written within JSON attributes only.
+	for _, a := range r.attrs {
+		s, ok := a.(StoreAttribute)
+		if !ok {
+			continue
+		}
+		store, ok := r.attrs[s.Store]
+		if !ok {
+			return &ErrUnknownAttribute{RecordName: r.rel.name, Attr: s.Store}
+		}
+		if _, ok := underlyingType(store.AttributeType()).(*JSON); !ok {
+			return fmt.Errorf(
+				"store %q of attribute %q is not a JSON attribute of %s", s.Store, s.AttributeName(), r.rel.name,
+			)
+		}
+	}
 	return nil
 }
 
+// deletedScope defines visibility of softly deleted records.
+type deletedScope int
+
+const (
+	excludeDeleted deletedScope = iota
+	withDeleted
+	onlyDeleted
+)
+
 type Relation struct {
 	name      string
 	tableName string
 	// TODO: add *Reflection property.
 	// reflection *Reflection
 
-	conn        Conn
-	connections *connectionHandler
+	conn           Conn
+	connections    *connectionHandler
+	connectionName string
+
+	scope    *attributes
+	defaults Hash
+	query    *QueryBuilder
+	ctx      context.Context
+
+	softDelete string
+	deleted    deletedScope
+
+	scopes        map[string]func(*Relation) *Relation
+	defaultScopes []func(*Relation) *Relation
+	unscoped      bool
 
-	scope *attributes
-	query *QueryBuilder
-	ctx   context.Context
+	nested map[string]NestedAttributes
 
 	associations
 	validations
@@ -137,15 +446,24 @@ type Relation struct {
 }
 
 func New(name string, init ...func(*R)) *Relation {
+	return defaultRegistry.New(name, init...)
+}
+
+func Initialize(name string, init func(*R)) (*Relation, error) {
+	return defaultRegistry.Initialize(name, init)
+}
+
+// New creates a new model within the registry, method panics on error.
+func (reg *Registry) New(name string, init ...func(*R)) *Relation {
 	var (
 		rel *Relation
 		err error
 	)
 	switch len(init) {
 	case 0:
-		rel, err = Initialize(name, nil)
+		rel, err = reg.Initialize(name, nil)
 	case 1:
-		rel, err = Initialize(name, init[0])
+		rel, err = reg.Initialize(name, init[0])
 	default:
 		panic(&ErrMultipleVariadicArguments{Name: "init"})
 	}
@@ -156,7 +474,8 @@ func New(name string, init ...func(*R)) *Relation {
 	return rel
 }
 
-func Initialize(name string, init func(*R)) (*Relation, error) {
+// Initialize creates a new model within the registry.
+func (reg *Registry) Initialize(name string, init func(*R)) (*Relation, error) {
 	rel := &Relation{name: name}
 
 	r := R{
@@ -164,17 +483,29 @@ func Initialize(name string, init func(*R)) (*Relation, error) {
 		assocs:      make(associationsMap),
 		attrs:       make(attributesMap),
 		validators:  make(validatorsMap),
-		reflection:  globalReflection,
-		connections: globalConnectionHandler,
+		defaults:    make(Hash),
+		enums:       make(map[string][]string),
+		encrypts:    make(map[string]*Encrypted),
+		scopes:      make(map[string]func(*Relation) *Relation),
+		nested:      make(map[string]NestedAttributes),
+		reflection:  reg.reflection,
+		connections: reg.connections,
 	}
 
-	err := r.init(context.TODO(), name+"s")
-	if err != nil {
-		return nil, err
-	}
 	if init != nil {
 		init(&r)
 	}
+	if r.tableName == "" {
+		r.tableName = name + "s"
+	}
+	if r.connectionName == "" {
+		r.connectionName = primaryConnectionName
+	}
+
+	err := r.init(context.TODO(), r.tableName)
+	if err != nil {
+		return nil, err
+	}
 
 	// When the primary key was assigned to record builder, mark it explicitely
 	// wrapping with PrimaryKey structure. Otherwise, fallback to the default primary
@@ -186,8 +517,22 @@ func Initialize(name string, init func(*R)) (*Relation, error) {
 		}
 		r.attrs[r.primaryKey] = PrimaryKey{Attribute: attr}
 	}
-	if r.tableName == "" {
-		r.tableName = name + "s"
+
+	if r.softDelete != "" {
+		if _, ok := r.attrs[r.softDelete]; !ok {
+			return nil, &ErrUnknownAttribute{RecordName: name, Attr: r.softDelete}
+		}
+	}
+
+	for assocName := range r.nested {
+		assoc, ok := r.assocs[assocName]
+		if !ok {
+			return nil, ErrUnknownAssociation{RecordName: name, Assoc: assocName}
+		}
+		if _, ok := assoc.(*HasMany); !ok {
+			message := fmt.Sprintf("'%s' is not a has-many association", assocName)
+			return nil, ErrAssociation{Message: message}
+		}
 	}
 
 	// The scope is empty by default.
@@ -202,9 +547,15 @@ func Initialize(name string, init func(*R)) (*Relation, error) {
 	// Create the model schema, and register it within a reflection instance.
 	rel.tableName = r.tableName
 	rel.scope = scope
+	rel.defaults = r.defaults
 	rel.associations = *assocs
 	rel.validations = *validations
 	rel.connections = r.connections
+	rel.connectionName = r.connectionName
+	rel.softDelete = r.softDelete
+	rel.scopes = r.scopes
+	rel.defaultScopes = r.defaultScopes
+	rel.nested = r.nested
 	rel.query = &QueryBuilder{from: r.tableName}
 	rel.AttributeMethods = scope
 	r.reflection.AddReflection(name, rel)
@@ -226,11 +577,19 @@ func (rel *Relation) Copy() *Relation {
 	return &Relation{
 		name:             rel.name,
 		tableName:        rel.tableName,
-		conn:             rel.Connection(),
+		conn:             rel.conn,
 		connections:      rel.connections,
+		connectionName:   rel.connectionName,
 		scope:            rel.scope.copy(),
+		defaults:         rel.defaults,
 		query:            rel.query.copy(),
 		ctx:              rel.ctx,
+		softDelete:       rel.softDelete,
+		deleted:          rel.deleted,
+		scopes:           rel.scopes,
+		defaultScopes:    rel.defaultScopes,
+		unscoped:         rel.unscoped,
+		nested:           rel.nested,
 		associations:     *rel.associations.copy(),
 		validations:      *rel.validations.copy(),
 		AttributeMethods: scope,
@@ -267,12 +626,17 @@ func (rel *Relation) Connect(conn Conn) *Relation {
 	return newrel
 }
 
+// ConnectionName returns the name of the database connection used by the relation.
+func (rel *Relation) ConnectionName() string {
+	return rel.connectionName
+}
+
 func (rel *Relation) Connection() Conn {
 	if rel.conn != nil {
 		return rel.conn
 	}
 
-	conn, err := rel.connections.RetrieveConnection(primaryConnectionName)
+	conn, err := rel.connections.RetrieveConnection(rel.connectionName)
 	if err != nil {
 		return &errConn{err: err}
 	}
@@ -291,6 +655,23 @@ func (rel *Relation) New(params ...map[string]interface{}) RecordResult {
 }
 
 func (rel *Relation) Initialize(params map[string]interface{}) (*ActiveRecord, error) {
+	// Assign default values of attributes, which are not given explicitly.
+	if len(rel.defaults) != 0 {
+		newParams := make(Hash, len(params)+len(rel.defaults))
+		for attrName, value := range rel.defaults {
+			if rel.scope.HasAttribute(attrName) {
+				newParams[attrName] = value
+			}
+		}
+		for attrName, value := range params {
+			newParams[attrName] = value
+		}
+		params = newParams
+	}
+
+	// Nested attributes are saved along with the record.
+	params, nested := rel.splitNestedAttributes(params)
+
 	attributes := rel.scope.clear()
 	err := attributes.AssignAttributes(params)
 	if err != nil {
@@ -301,7 +682,9 @@ func (rel *Relation) Initialize(params map[string]interface{}) (*ActiveRecord, e
 		name:         rel.name,
 		tableName:    rel.tableName,
 		conn:         rel.Connection(),
+		relation:     rel,
 		attributes:   attributes,
+		nested:       nested,
 		associations: rel.associations.copy(),
 		validations:  *rel.validations.copy(),
 	}
@@ -313,17 +696,16 @@ func (rel *Relation) Create(params map[string]interface{}) RecordResult {
 }
 
 func (rel *Relation) ExtractRecord(h Hash) (*ActiveRecord, error) {
-	var (
-		attrNames   = rel.scope.AttributeNames()
-		columnNames = rel.scope.ColumnNames()
-	)
+	attrs := rel.scope.AttributesForInspect()
 
-	params := make(Hash, len(attrNames))
-	for i, colName := range columnNames {
-		attrName := attrNames[i]
-		attr := rel.scope.AttributeForInspect(attrName)
+	params := make(Hash, len(attrs))
+	for _, attr := range attrs {
+		if isVirtual(attr) {
+			continue
+		}
 
-		attrValue, err := attr.AttributeType().Deserialize(h[colName])
+		attrName := attr.AttributeName()
+		attrValue, err := attr.AttributeType().Deserialize(h[rel.scope.columnName(attrName)])
 		if err != nil {
 			return nil, err
 		}
@@ -338,6 +720,21 @@ func (rel *Relation) PrimaryKey() string {
 	return rel.scope.PrimaryKey()
 }
 
+// LockingColumn returns the name of the column used for optimistic locking, or
+// empty string, when records of the relation are not locked optimistically.
+func (rel *Relation) LockingColumn() string {
+	if rel.scope.HasAttribute(lockingColumn) {
+		return lockingColumn
+	}
+	return ""
+}
+
+// SoftDeleteColumn returns the name of the column storing the time of deletion,
+// or empty string, when records of the relation are deleted permanently.
+func (rel *Relation) SoftDeleteColumn() string {
+	return rel.softDelete
+}
+
 func (rel *Relation) All() CollectionResult {
 	return OkCollection(rel)
 }
@@ -347,8 +744,75 @@ func (rel *Relation) ColumnNames() []string {
 	return rel.scope.ColumnNames()
 }
 
+// WithDeleted returns a new relation, which includes softly deleted records.
+func (rel *Relation) WithDeleted() *Relation {
+	newrel := rel.Copy()
+	newrel.deleted = withDeleted
+	return newrel
+}
+
+// OnlyDeleted returns a new relation, which includes only softly deleted records.
+func (rel *Relation) OnlyDeleted() *Relation {
+	newrel := rel.Copy()
+	newrel.deleted = onlyDeleted
+	return newrel
+}
+
+// Scope returns a new relation with the named scope applied. Method returns
+// ErrUnknownScope, when the scope is not defined.
+//
+//	books := author.Collection("books").Scope("recent")
+func (rel *Relation) Scope(name string) CollectionResult {
+	fn, ok := rel.scopes[name]
+	if !ok {
+		return ErrCollection(&ErrUnknownScope{RecordName: rel.name, Scope: name})
+	}
+	return OkCollection(fn(rel.Copy()))
+}
+
+// Unscoped returns a new relation without default scopes, the relation also
+// includes softly deleted records.
+func (rel *Relation) Unscoped() *Relation {
+	newrel := rel.Copy()
+	newrel.unscoped = true
+	return newrel
+}
+
+// scoped returns a copy of the query builder restricted by the default scopes
+// and the visibility of softly deleted records.
+func (rel *Relation) scoped(q *QueryBuilder) *QueryBuilder {
+	q = q.copy()
+	if rel.unscoped {
+		return q
+	}
+
+	if len(rel.defaultScopes) != 0 {
+		// Default scopes are applied to the blank relation, so conditions
+		// are not duplicated.
+		newrel := rel.Copy()
+		newrel.query = &QueryBuilder{from: rel.tableName}
+		for _, fn := range rel.defaultScopes {
+			newrel = fn(newrel)
+		}
+		q.whereValues = append(q.whereValues, newrel.query.whereValues...)
+	}
+
+	if rel.softDelete == "" {
+		return q
+	}
+
+	column := fmt.Sprintf("%q.%q", rel.tableName, rel.softDelete)
+	switch rel.deleted {
+	case excludeDeleted:
+		q.Where(column + " IS NULL")
+	case onlyDeleted:
+		q.Where(column + " IS NOT NULL")
+	}
+	return q
+}
+
 func (rel *Relation) Each(fn func(*ActiveRecord) error) error {
-	q := rel.query.copy()
+	q := rel.scoped(rel.query)
 	q.Select(rel.ColumnNames()...)
 
 	// Include all join dependencies into the query with fully-qualified column
@@ -392,8 +856,16 @@ func (rel *Relation) Where(cond string, arg interface{}) *Relation {
 
 	// When the condition is a regular column, pass it through the regular
 	// column comparison instead of query chain predicates.
+	if attr, ok := newrel.scope.AttributeForInspect(cond).(StoreAttribute); ok {
+		return rel.WhereJSON(attr.Store+"."+cond, arg)
+	}
 	if newrel.scope.HasAttribute(cond) {
-		// newrel.scope.AssignAttribute(cond, arg)
+		// Serialize the value in the same way as it is stored in the database,
+		// e.g. enum values backed by integer columns are compared by indexes.
+		attr := newrel.scope.AttributeForInspect(cond)
+		if value, err := attr.AttributeType().Serialize(arg); err == nil {
+			arg = value
+		}
 		newrel.query.Where(fmt.Sprintf("%s = ?", cond), arg)
 	} else {
 		newrel.query.Where(cond, arg)
@@ -401,6 +873,28 @@ func (rel *Relation) Where(cond string, arg interface{}) *Relation {
 	return newrel
 }
 
+// WhereJSON returns a new relation, which is the result of filtering the
+// current relation by the value at the path within the JSON attribute. The
+// path consists of the attribute name and keys separated by dots.
+//
+//	User.WhereJSON("settings.notifications.email", true)
+//
+// The relation is empty, when the attribute is unknown.
+//
+// SQLite adapter extracts values with json_extract function.
+func (rel *Relation) WhereJSON(path string, arg interface{}) *Relation {
+	newrel := rel.Copy()
+
+	keys := strings.Split(path, ".")
+	if !newrel.scope.HasAttribute(keys[0]) || len(keys) < 2 {
+		return newrel.empty()
+	}
+
+	expr := newrel.Connection().JSONExtract(keys[0], keys[1:]...)
+	newrel.query.Where(fmt.Sprintf("%s = ?", expr), arg)
+	return newrel
+}
+
 // Select allows to specify a subset of fields to return.
 //
 // Method returns a new relation, where a set of attributes is limited by the
@@ -446,6 +940,29 @@ func (rel *Relation) Group(attrNames ...string) *Relation {
 	return newrel
 }
 
+// Order specifies the order of retrieved records, each value is an attribute
+// name optionally followed by ASC or DESC direction.
+//
+//	Book.Order("year DESC", "title")
+//
+// When the attribute is not part of the scope, the relation is empty.
+func (rel *Relation) Order(values ...string) *Relation {
+	newrel := rel.Copy()
+
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) == 0 || len(fields) > 2 || !newrel.scope.HasAttribute(fields[0]) {
+			return newrel.empty()
+		}
+		if len(fields) == 2 && !Strings("ASC", "DESC").Contains(strings.ToUpper(fields[1])) {
+			return newrel.empty()
+		}
+	}
+
+	newrel.query.Order(values...)
+	return newrel
+}
+
 // Limit specifies a limit for the number of records to retrieve.
 //
 //	User.Limit(10) // Generated SQL has 'LIMIT 10'
@@ -455,6 +972,41 @@ func (rel *Relation) Limit(num int) *Relation {
 	return newrel
 }
 
+// Lock specifies a row-level locking clause of the query, the rows are locked
+// in FOR UPDATE mode, unless another mode is given.
+//
+//	Book.Lock().Where("id", 1).ToSQL()
+//	// SELECT * FROM "books" WHERE (id = ?) FOR UPDATE
+//
+//	Book.Lock(activerecord.LockForUpdateSkipLocked).Limit(1)
+//	// SELECT * FROM "books" LIMIT 1 FOR UPDATE SKIP LOCKED
+//
+// Locks are held until the end of the transaction, therefore the relation is
+// expected to be used within the transaction.
+//
+// Adapters without row-level locks (e.g. SQLite) lock the whole database at the
+// beginning of the transaction started with ContextWithLock instead, the lock
+// cannot be acquired later. Locking queries outside of such transaction fail
+// with ErrUnsupportedLock, rather than silently locking nothing:
+//
+//	ctx = activerecord.ContextWithLock(ctx, activerecord.LockForUpdate)
+//	err := activerecord.Transaction(ctx, func() error {
+//		book := Book.Lock().Find(1)
+//		return book.AssignAttributes(Hash{"title": "Dune"}).Update().Err()
+//	})
+func (rel *Relation) Lock(mode ...LockMode) *Relation {
+	newrel := rel.Copy()
+	switch len(mode) {
+	case 0:
+		newrel.query.Lock(LockForUpdate)
+	case 1:
+		newrel.query.Lock(mode[0])
+	default:
+		panic(&ErrMultipleVariadicArguments{Name: "mode"})
+	}
+	return newrel
+}
+
 func (rel *Relation) Joins(assocNames ...string) *Relation {
 	newrel := rel.Copy()
 
@@ -470,11 +1022,11 @@ func (rel *Relation) Joins(assocNames ...string) *Relation {
 }
 
 func (rel *Relation) Find(id interface{}) RecordResult {
-	var q QueryBuilder
-	q.From(rel.TableName())
-	q.Select(rel.scope.AttributeNames()...)
+	q := rel.scoped(&QueryBuilder{from: rel.TableName()})
+	q.Select(rel.ColumnNames()...)
 	// TODO: consider using unified approach.
 	q.Where(fmt.Sprintf("%s = ?", rel.PrimaryKey()), id)
+	q.Lock(rel.query.lock)
 
 	var rows []Hash
 
@@ -488,7 +1040,7 @@ func (rel *Relation) Find(id interface{}) RecordResult {
 	if len(rows) != 1 {
 		return ErrRecord(&ErrRecordNotFound{PrimaryKey: rel.PrimaryKey(), ID: id})
 	}
-	return rel.New(rows[0])
+	return ReturnRecord(rel.ExtractRecord(rows[0]))
 }
 
 // FindBy returns a record matching the specified condition.
@@ -516,6 +1068,72 @@ func (rel *Relation) First() RecordResult {
 	}
 }
 
+// defaultBatchSize is a number of records retrieved by FindEach at once.
+const defaultBatchSize = 1000
+
+// FindInBatches calls the function with batches of records of the given size.
+// Records are paged by the primary key, so each batch is retrieved with a
+// short query and the function is called, when the query is complete. This
+// makes it safe to modify records within the function.
+//
+//	err := Book.Where("year < ?", 1900).FindInBatches(100, func(books activerecord.Array) error {
+//		return archive(books)
+//	})
+//
+// Order and limit of the relation are ignored, records are ordered by the
+// primary key.
+func (rel *Relation) FindInBatches(size int, fn func(Array) error) error {
+	if size <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", size)
+	}
+
+	primaryKey := fmt.Sprintf("%q.%q", rel.TableName(), rel.PrimaryKey())
+
+	batchrel := rel.Copy()
+	batchrel.query.orderValues = []string{primaryKey}
+	batchrel.query.Limit(size)
+
+	var lastID interface{}
+	for {
+		batch := batchrel
+		if lastID != nil {
+			batch = batchrel.Copy()
+			batch.query.Where(primaryKey+" > ?", lastID)
+		}
+
+		records, err := batch.ToA()
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err = fn(records); err != nil {
+			return err
+		}
+		if len(records) < size {
+			return nil
+		}
+		lastID = records[len(records)-1].ID()
+	}
+}
+
+// FindEach calls the function for each record, records are retrieved in
+// batches (see FindInBatches).
+func (rel *Relation) FindEach(fn func(*ActiveRecord) error) error {
+	return rel.FindInBatches(defaultBatchSize, func(records Array) error {
+		for _, rec := range records {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InsertAll validates and inserts records with the given attributes within a
+// single transaction. Consecutive records with the same set of attributes are
+// inserted with a single multi-row statement.
 func (rel *Relation) InsertAll(params ...map[string]interface{}) (
 	rr []*ActiveRecord, err error,
 ) {
@@ -525,23 +1143,393 @@ func (rel *Relation) InsertAll(params ...map[string]interface{}) (
 		if err != nil {
 			return nil, err
 		}
+		if err = rec.prepareInsert(); err != nil {
+			return nil, err
+		}
 
 		rr = append(rr, rec)
 	}
 
-	if err = rel.connections.Transaction(rel.Context(), func() error {
-		for i, rec := range rr {
-			if rr[i], err = rec.Insert(); err != nil {
+	rows := make([][]ColumnValue, len(rr))
+	for i, rec := range rr {
+		rows[i] = rec.columnValues()
+	}
+
+	primaryKey := rel.PrimaryKey()
+	_, autoincrement := underlyingType(rel.scope.AttributeForInspect(primaryKey).AttributeType()).(*Int64)
+
+	if err = rel.connections.Transaction(rel.Context(), rel.connectionName, func() error {
+		for _, group := range groupColumnValues(rows) {
+			op := InsertOperation{TableName: rel.tableName}
+			for _, i := range group {
+				op.Rows = append(op.Rows, rows[i])
+			}
+			if autoincrement {
+				op.Returning = primaryKey
+			}
+
+			id, err := rel.Connection().ExecInsert(rel.Context(), &op)
+			if err != nil {
 				return err
 			}
+
+			// Identifiers assigned by the database are returned in the order
+			// of inserted rows, when some rows are not returned, there is no
+			// way to match identifiers with records.
+			ids, ok := id.([]interface{})
+			if !ok || len(ids) != len(group) {
+				continue
+			}
+			for pos, i := range group {
+				if rr[i].Attribute(primaryKey) != nil {
+					continue
+				}
+				if err = rr[i].AssignAttribute(primaryKey, ids[pos]); err != nil {
+					return err
+				}
+			}
 		}
-		return nil
+		return rel.updateCounterCaches(rel.Context(), rr, 1)
 	}); err != nil {
 		return nil, err
 	}
 	return rr, nil
 }
 
+// UpsertOption configures the conflict handling of UpsertAll.
+type UpsertOption func(*InsertOperation)
+
+// ConflictTarget defines columns of the unique index, conflicts of which
+// are resolved by the update of existing rows. By default the primary key
+// is used.
+func ConflictTarget(columnNames ...string) UpsertOption {
+	return func(op *InsertOperation) {
+		op.ConflictTarget = strings.Join(columnNames, ",")
+	}
+}
+
+// UpsertAll inserts rows with the given attributes, rows conflicting with
+// existing ones are updated instead. Rows are written as is, without model
+// defaults, callbacks and validations.
+//
+//	err := User.UpsertAll([]map[string]interface{}{
+//		{"email": "jules@example.com", "name": "Jules"},
+//	}, activerecord.ConflictTarget("email"))
+func (rel *Relation) UpsertAll(params []map[string]interface{}, options ...UpsertOption) error {
+	rows := make([][]ColumnValue, 0, len(params))
+	for _, h := range params {
+		row, err := rel.columnValues(h)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	return rel.connections.Transaction(rel.Context(), rel.connectionName, func() error {
+		for _, group := range groupColumnValues(rows) {
+			op := InsertOperation{
+				TableName:      rel.tableName,
+				OnDuplicate:    OnDuplicateUpdate,
+				ConflictTarget: rel.PrimaryKey(),
+			}
+			for _, option := range options {
+				option(&op)
+			}
+			for _, i := range group {
+				op.Rows = append(op.Rows, rows[i])
+			}
+
+			if _, err := rel.Connection().ExecInsert(rel.Context(), &op); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateAll updates all records of the relation with a single statement.
+// Values are written as is, without callbacks, validations and locking.
+//
+//	err := Book.Where("year < ?", 1900).UpdateAll(Hash{"archived": true})
+func (rel *Relation) UpdateAll(params Hash) error {
+	if len(rel.query.joinValues) != 0 {
+		return fmt.Errorf("update of joined relation %q is not supported", rel.name)
+	}
+
+	columnValues, err := rel.columnValues(params)
+	if err != nil {
+		return err
+	}
+	if len(columnValues) == 0 {
+		return nil
+	}
+
+	return rel.Connection().ExecUpdate(rel.Context(), &UpdateOperation{
+		TableName:    rel.tableName,
+		ColumnValues: columnValues,
+		Where:        rel.scoped(rel.query).whereValues,
+	})
+}
+
+// DeleteAll deletes all records of the relation with a single statement,
+// records of models with soft deletion are marked as deleted.
+//
+//	err := Book.Where("year < ?", 1900).DeleteAll()
+//
+// When the model caches counters of associations, deleted records are read
+// before the deletion to decrement counters.
+func (rel *Relation) DeleteAll() error {
+	if len(rel.query.joinValues) != 0 {
+		return fmt.Errorf("deletion of joined relation %q is not supported", rel.name)
+	}
+	if len(rel.counterCaches()) == 0 {
+		return rel.deleteAll()
+	}
+
+	// Softly deleted records are not counted already.
+	var records []*ActiveRecord
+	err := rel.Each(func(rec *ActiveRecord) error {
+		if !rec.IsDeleted() {
+			records = append(records, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return rel.connections.Transaction(rel.Context(), rel.connectionName, func() error {
+		if err := rel.deleteAll(); err != nil {
+			return err
+		}
+		return rel.updateCounterCaches(rel.Context(), records, -1)
+	})
+}
+
+func (rel *Relation) deleteAll() error {
+	if rel.softDelete != "" {
+		return rel.UpdateAll(Hash{rel.softDelete: time.Now().UTC()})
+	}
+
+	return rel.Connection().ExecDelete(rel.Context(), &DeleteOperation{
+		TableName: rel.tableName,
+		Where:     rel.scoped(rel.query).whereValues,
+	})
+}
+
+// UpdateCounters increments columns of all records of the relation by the
+// given values with a single statement, negative values decrement columns.
+//
+//	err := Author.Where("id", 1).UpdateCounters(map[string]int{"books_count": 1})
+func (rel *Relation) UpdateCounters(counters map[string]int) error {
+	if len(rel.query.joinValues) != 0 {
+		return fmt.Errorf("update of joined relation %q is not supported", rel.name)
+	}
+
+	params := make(Hash, len(counters))
+	for name, value := range counters {
+		params[name] = int64(value)
+	}
+	increments, err := rel.columnValues(params)
+	if err != nil {
+		return err
+	}
+	if len(increments) == 0 {
+		return nil
+	}
+
+	return rel.Connection().ExecUpdate(rel.Context(), &UpdateOperation{
+		TableName:  rel.tableName,
+		Increments: increments,
+		Where:      rel.scoped(rel.query).whereValues,
+	})
+}
+
+// ResetCounters recounts cached numbers of targets of the given collections
+// for all records of the relation. Use it to fix counters after bulk
+// operations, which bypass counter caches (e.g. UpsertAll, UpdateAll).
+//
+//	err := Author.ResetCounters("books")
+func (rel *Relation) ResetCounters(collNames ...string) error {
+	for _, collName := range collNames {
+		assoc, ok := rel.associations.keys[collName].(*HasMany)
+		if !ok {
+			return ErrUnknownAssociation{RecordName: rel.name, Assoc: collName}
+		}
+
+		column := assoc.CounterCacheColumn()
+		if column == "" {
+			message := fmt.Sprintf("'%s' has no counter cache", collName)
+			return ErrAssociation{Message: message}
+		}
+		if !rel.scope.HasAttribute(column) {
+			return &ErrUnknownAttribute{RecordName: rel.name, Attr: column}
+		}
+
+		targets, err := assoc.reflection.Reflection(assoc.targetName)
+		if err != nil {
+			return err
+		}
+
+		// Counters include all targets, except softly deleted ones, targets
+		// are counted by the database.
+		foreignKey := assoc.inverseOf().AssociationForeignKey()
+		q := QueryBuilder{from: targets.tableName}
+		q.Select(foreignKey, "COUNT(*)")
+		if targets.softDelete != "" {
+			q.Where(targets.softDelete + " IS NULL")
+		}
+		q.Where(foreignKey + " IS NOT NULL")
+		q.Group(foreignKey)
+
+		err = rel.connections.Transaction(rel.Context(), rel.connectionName, func() error {
+			// Read all counts before updating counters, since not all adapters
+			// support writing, while reading cursor is open.
+			var rows []Hash
+			err := targets.Connection().ExecQuery(rel.Context(), q.Operation(), func(row Hash) bool {
+				rows = append(rows, row)
+				return true
+			})
+			if err != nil {
+				return err
+			}
+
+			// Owners without targets are not returned by the query.
+			if err = rel.UpdateAll(Hash{column: int64(0)}); err != nil {
+				return err
+			}
+			for _, row := range rows {
+				owner := rel.Where(rel.PrimaryKey(), row[foreignKey])
+				if err = owner.UpdateAll(Hash{column: row["COUNT(*)"]}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// counterCaches returns BelongsTo associations, which cache counters of
+// records in targets.
+func (rel *Relation) counterCaches() []*BelongsTo {
+	var assocs []*BelongsTo
+	for _, name := range rel.associations.AssociationNames() {
+		assoc, ok := rel.associations.keys[name].(*BelongsTo)
+		if ok && assoc.CounterCacheColumn() != "" {
+			assocs = append(assocs, assoc)
+		}
+	}
+	return assocs
+}
+
+// counterCacheColumns returns columns caching the number of targets of
+// collections.
+func (rel *Relation) counterCacheColumns() map[string]bool {
+	columns := make(map[string]bool)
+	for _, assoc := range rel.associations.keys {
+		if assoc, ok := assoc.(*HasMany); ok {
+			if column := assoc.CounterCacheColumn(); column != "" {
+				columns[column] = true
+			}
+		}
+	}
+	return columns
+}
+
+// updateCounterCaches changes counters of targets referenced by records by
+// delta, each target is updated once.
+func (rel *Relation) updateCounterCaches(ctx context.Context, records []*ActiveRecord, delta int) error {
+	for _, assoc := range rel.counterCaches() {
+		var (
+			targetIDs []interface{}
+			counts    = make(map[interface{}]int)
+		)
+		for _, rec := range records {
+			targetID := rec.Attribute(assoc.AssociationForeignKey())
+			if targetID == nil {
+				continue
+			}
+			if _, ok := counts[targetID]; !ok {
+				targetIDs = append(targetIDs, targetID)
+			}
+			counts[targetID]++
+		}
+
+		for _, targetID := range targetIDs {
+			if err := assoc.updateCounter(ctx, targetID, counts[targetID]*delta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reassignCounterCaches moves counters of the record from targets referenced
+// by the previous version of the record to the current ones.
+func (rel *Relation) reassignCounterCaches(ctx context.Context, prev, rec *ActiveRecord) error {
+	for _, assoc := range rel.counterCaches() {
+		prevID := prev.Attribute(assoc.AssociationForeignKey())
+		targetID := rec.Attribute(assoc.AssociationForeignKey())
+		if reflect.DeepEqual(prevID, targetID) {
+			continue
+		}
+		if err := assoc.updateCounter(ctx, prevID, -1); err != nil {
+			return err
+		}
+		if err := assoc.updateCounter(ctx, targetID, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnValues returns values of the given attributes sorted by name.
+func (rel *Relation) columnValues(params map[string]interface{}) ([]ColumnValue, error) {
+	columnValues := make([]ColumnValue, 0, len(params))
+	for name, value := range params {
+		attr := rel.scope.AttributeForInspect(name)
+		if attr == nil || isVirtual(attr) {
+			return nil, &ErrUnknownAttribute{RecordName: rel.name, Attr: name}
+		}
+		columnValues = append(columnValues, ColumnValue{
+			Name: name, Type: attr.AttributeType(), Value: value,
+		})
+	}
+
+	sort.Slice(columnValues, func(i, j int) bool {
+		return columnValues[i].Name < columnValues[j].Name
+	})
+	return columnValues, nil
+}
+
+// groupColumnValues returns positions of rows grouped by the set of columns.
+// Only consecutive rows are grouped, so rows are inserted in the given order.
+// Columns of rows must be sorted.
+func groupColumnValues(rows [][]ColumnValue) [][]int {
+	var (
+		groups  [][]int
+		lastKey string
+	)
+	for i, row := range rows {
+		names := make([]string, len(row))
+		for j := range row {
+			names[j] = row[j].Name
+		}
+
+		key := strings.Join(names, ",")
+		if len(groups) == 0 || key != lastKey {
+			groups = append(groups, nil)
+			lastKey = key
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], i)
+	}
+	return groups
+}
+
 // ToA converts Relation to array. The method access database to retrieve objects.
 func (rel *Relation) ToA() (Array, error) {
 	var rr Array
@@ -561,7 +1549,7 @@ func (rel *Relation) ToA() (Array, error) {
 //	User.Where("name", "Oscar").ToSQL()
 //	// SELECT * FROM "users" WHERE "name" = ?
 func (rel *Relation) ToSQL() string {
-	return rel.query.String()
+	return rel.scoped(rel.query).String()
 }
 
 func (rel *Relation) String() string {
@@ -0,0 +1,54 @@
+package activerecord_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+func TestRelation_WhereJSON(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("name")
+			t.JSON("settings")
+		})
+	})
+
+	User := activerecord.New("user", func(r *activerecord.R) {
+		r.Store("settings", "theme", "locale")
+	})
+
+	User.Create(Hash{
+		"name": "Jules", "settings": Hash{"notifications": Hash{"email": true}}, "theme": "dark",
+	}).Unwrap()
+	User.Create(Hash{"name": "Victor", "locale": "fr"}).Unwrap()
+
+	names := func(rel *activerecord.Relation) []interface{} {
+		records, err := rel.ToA()
+		require.NoError(t, err)
+
+		names := make([]interface{}, 0, len(records))
+		for _, rec := range records {
+			names = append(names, rec.Attribute("name"))
+		}
+		return names
+	}
+
+	require.Equal(t, []interface{}{"Jules"}, names(User.WhereJSON("settings.theme", "dark")))
+	require.Equal(t, []interface{}{"Jules"}, names(User.WhereJSON("settings.notifications.email", true)))
+	require.Equal(t, []interface{}{"Victor"}, names(User.Where("locale", "fr")))
+	require.Empty(t, names(User.WhereJSON("settings.theme", "light")))
+}
@@ -2,6 +2,7 @@ package activerecord_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -159,3 +160,447 @@ func TestRelation_TransactionalInsert(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, book, 1)
 }
+
+func TestRelation_Lock(t *testing.T) {
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	initAuthorTable(t, conn)
+
+	Author := activerecord.New("author")
+	Author.Create(Hash{"name": "Jules Verne"}).Expect("author was not created")
+
+	require.Equal(t,
+		`SELECT * FROM "authors" WHERE (name = ?) FOR UPDATE`,
+		Author.Lock().Where("name", "Jules Verne").ToSQL(),
+	)
+	require.Equal(t,
+		`SELECT * FROM "authors" LIMIT 1 FOR UPDATE SKIP LOCKED`,
+		Author.Lock(activerecord.LockForUpdateSkipLocked).Limit(1).ToSQL(),
+	)
+
+	// SQLite does not support row-level locks, the database is locked by
+	// the transaction started with the locking context.
+	ctx := activerecord.ContextWithLock(context.TODO(), activerecord.LockForUpdate)
+	err = activerecord.Transaction(ctx, func() error {
+		authors, err := Author.Lock().Where("name", "Jules Verne").ToA()
+		require.Len(t, authors, 1)
+		return err
+	})
+	require.NoError(t, err)
+
+	// Otherwise the locking query fails instead of locking nothing, both
+	// within the ordinary transaction and outside of any transaction.
+	err = activerecord.Transaction(context.TODO(), func() error {
+		_, err := Author.Lock().Where("name", "Jules Verne").ToA()
+		return err
+	})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, new(activerecord.ErrUnsupportedLock)), err)
+	require.EqualError(t, err,
+		"FOR UPDATE lock is not supported, transaction must be started with activerecord.ContextWithLock",
+	)
+
+	_, err = Author.Lock(activerecord.LockForShare).ToA()
+	require.True(t, errors.Is(err, new(activerecord.ErrUnsupportedLock)), err)
+}
+
+func TestRelation_WhereJSON_ToSQL(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("name")
+			t.JSON("settings")
+		})
+	})
+
+	User := activerecord.New("user", func(r *activerecord.R) {
+		r.Store("settings", "theme")
+	})
+
+	// Results of queries are checked in relation_json_test.go.
+	require.Equal(t,
+		`SELECT * FROM "users" WHERE (json_extract("settings", '$."notifications"."email"') = ?)`,
+		User.WhereJSON("settings.notifications.email", true).ToSQL(),
+	)
+	require.Equal(t,
+		`SELECT * FROM "users" WHERE (json_extract("settings", '$."theme"') = ?)`,
+		User.Where("theme", "dark").ToSQL(),
+	)
+	require.Equal(t,
+		`SELECT * FROM "users" WHERE (json_extract("settings", '$."it''s"') = ?)`,
+		User.WhereJSON("settings.it's", "quoted").ToSQL(),
+	)
+}
+
+func TestRelation_SoftDelete(t *testing.T) {
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	initAuthorTable(t, conn)
+	initBookTable(t, conn)
+
+	activerecord.Migrate(t.Name()+"_add_books_deleted_at", func(m *activerecord.M) {
+		m.AddColumn("books", "deleted_at", new(activerecord.DateTime))
+	})
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.HasMany("books")
+	})
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.BelongsTo("author")
+		r.SoftDelete("deleted_at")
+	})
+
+	author := Author.Create(Hash{"name": "Jules Verne"})
+	author = author.AssignCollection("books",
+		Book.New(Hash{"title": "Nemo", "year": 1870}),
+		Book.New(Hash{"title": "Around the World", "year": 1872}),
+	)
+	require.NoError(t, author.Err())
+
+	book, err := Book.FindBy("title", "Nemo").Unwrap().Delete()
+	require.NoError(t, err)
+	require.True(t, book.IsDeleted())
+
+	// Deleted records are excluded by default.
+	books, err := Book.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	require.Error(t, Book.Find(book.ID()).Err())
+
+	books, err = author.Collection("books").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Around the World", books[0].Attribute("title"))
+
+	books, err = Book.WithDeleted().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+
+	books, err = Book.OnlyDeleted().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Nemo", books[0].Attribute("title"))
+	require.NotNil(t, books[0].Attribute("deleted_at"))
+
+	book, err = Book.OnlyDeleted().Find(book.ID()).Unwrap().Restore()
+	require.NoError(t, err)
+	require.False(t, book.IsDeleted())
+	require.Equal(t, "Nemo", Book.Find(book.ID()).Unwrap().Attribute("title"))
+
+	// Records are deleted permanently with HardDelete.
+	_, err = book.HardDelete()
+	require.NoError(t, err)
+
+	books, err = Book.WithDeleted().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	// Records of the relation are softly deleted with a single statement.
+	require.NoError(t, Book.All().DeleteAll())
+
+	books, err = Book.OnlyDeleted().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+
+	// Soft delete column must be an attribute of the model.
+	_, err = activerecord.Initialize("author", func(r *activerecord.R) {
+		r.SoftDelete("deleted_at")
+	})
+	require.Error(t, err)
+}
+
+func TestRelation_Scope(t *testing.T) {
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	initAuthorTable(t, conn)
+	initBookTable(t, conn)
+
+	Author := activerecord.New("author", func(r *activerecord.R) {
+		r.HasMany("books")
+	})
+	Book := activerecord.New("book", func(r *activerecord.R) {
+		r.BelongsTo("author")
+		r.Scope("recent", func(rel *activerecord.Relation) *activerecord.Relation {
+			return rel.Where("year >= ?", 1870)
+		})
+		r.DefaultScope(func(rel *activerecord.Relation) *activerecord.Relation {
+			return rel.Where("year > ?", 1860)
+		})
+	})
+
+	require.Panics(t, func() {
+		activerecord.New("book", func(r *activerecord.R) {
+			fn := func(rel *activerecord.Relation) *activerecord.Relation { return rel }
+			r.Scope("recent", fn)
+			r.Scope("recent", fn)
+		})
+	})
+
+	author := Author.Create(Hash{"name": "Jules Verne"})
+	author = author.AssignCollection("books",
+		Book.New(Hash{"title": "Five Weeks in a Balloon", "year": 1863}),
+		Book.New(Hash{"title": "Nemo", "year": 1870}),
+		Book.New(Hash{"title": "Moby Dick", "year": 1851}),
+	)
+	require.NoError(t, author.Err())
+
+	books, err := Book.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 2)
+
+	books, err = Book.Scope("recent").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Nemo", books[0].Attribute("title"))
+
+	books, err = Book.Unscoped().ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 3)
+
+	// Scopes are composed with associations.
+	books, err = author.Collection("books").Scope("recent").ToA()
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	require.Equal(t, "Nemo", books[0].Attribute("title"))
+
+	// Misspelled scopes are not silently ignored.
+	_, err = Book.Scope("recnet").ToA()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, new(activerecord.ErrUnknownScope)))
+	require.EqualError(t, err, `scope "recnet" is not defined for book`)
+
+	_, err = author.Collection("books").Scope("recnet").ToA()
+	require.True(t, errors.Is(err, new(activerecord.ErrUnknownScope)))
+}
+
+func TestRelation_Order(t *testing.T) {
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	initAuthorTable(t, conn)
+
+	Author := activerecord.New("author")
+	_, err = Author.InsertAll(Hash{"name": "B"}, Hash{"name": "C"}, Hash{"name": "A"})
+	require.NoError(t, err)
+
+	authors, err := Author.Order("name DESC").ToA()
+	require.NoError(t, err)
+	require.Len(t, authors, 3)
+	require.Equal(t, "C", authors[0].Attribute("name"))
+	require.Equal(t, "A", authors[2].Attribute("name"))
+
+	require.Contains(t, Author.Order("name").Limit(1).ToSQL(), "ORDER BY name LIMIT 1")
+}
+
+func TestRelation_FindInBatches(t *testing.T) {
+	conn, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	initProductTable(t, conn)
+
+	Product := activerecord.New("product")
+
+	params := make([]map[string]interface{}, 0, 25)
+	for i := 0; i < 25; i++ {
+		params = append(params, Hash{"name": "product"})
+	}
+	_, err = Product.InsertAll(params...)
+	require.NoError(t, err)
+
+	var sizes []int
+	err = Product.Order("name DESC").FindInBatches(10, func(products activerecord.Array) error {
+		sizes = append(sizes, len(products))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{10, 10, 5}, sizes)
+
+	// Records are updated within the iteration.
+	var lastID int64
+	err = Product.FindEach(func(product *activerecord.ActiveRecord) error {
+		require.Greater(t, product.ID().(int64), lastID)
+		lastID = product.ID().(int64)
+
+		if err := product.AssignAttribute("name", "updated"); err != nil {
+			return err
+		}
+		_, err := product.Update()
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(25), lastID)
+
+	products, err := Product.Where("name", "updated").ToA()
+	require.NoError(t, err)
+	require.Len(t, products, 25)
+
+	err = Product.FindInBatches(0, func(activerecord.Array) error { return nil })
+	require.Error(t, err)
+}
+
+func TestRelation_BulkOperations(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name()+"_create_users", func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.String("email")
+			t.String("name")
+			t.Int64("age")
+			t.Index("email", activerecord.Unique())
+		})
+	})
+
+	User := activerecord.New("user")
+
+	users, err := User.InsertAll(
+		Hash{"email": "jules@example.com", "name": "Jules", "age": 30},
+		Hash{"email": "victor@example.com", "name": "Victor"},
+		Hash{"email": "paul@example.com", "name": "Paul", "age": 40},
+	)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	for _, user := range users {
+		found := User.Find(user.ID()).Unwrap()
+		require.Equal(t, user.Attribute("email"), found.Attribute("email"))
+	}
+
+	err = User.UpsertAll([]map[string]interface{}{
+		{"email": "jules@example.com", "name": "Jules Verne"},
+		{"email": "jane@example.com", "name": "Jane"},
+	}, activerecord.ConflictTarget("email"))
+	require.NoError(t, err)
+
+	jules := User.FindBy("email", "jules@example.com").Unwrap()
+	require.Equal(t, "Jules Verne", jules.Attribute("name"))
+	require.Equal(t, int64(30), jules.Attribute("age"))
+
+	all, err := User.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+
+	err = User.UpsertAll([]map[string]interface{}{{"unknown": 1}})
+	require.Error(t, err)
+
+	// Only identifiers of inserted rows are returned, rows skipped on
+	// conflict are not, so identifiers are never guessed from the last one.
+	conn, err := activerecord.RetrieveConnection("primary")
+	require.NoError(t, err)
+
+	email := func(value string) []activerecord.ColumnValue {
+		return []activerecord.ColumnValue{{Name: "email", Type: new(activerecord.String), Value: value}}
+	}
+	ids, err := conn.ExecInsert(context.TODO(), &activerecord.InsertOperation{
+		TableName:      "users",
+		Rows:           [][]activerecord.ColumnValue{email("new@example.com"), email("jane@example.com")},
+		OnDuplicate:    activerecord.OnDuplicateSkip,
+		ConflictTarget: "email",
+		Returning:      "id",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{User.FindBy("email", "new@example.com").Unwrap().ID()}, ids)
+	require.NoError(t, User.Where("email", "new@example.com").DeleteAll())
+
+	// Records with conflicting rows are not inserted at all.
+	_, err = User.InsertAll(
+		Hash{"email": "ann@example.com", "name": "Ann"},
+		Hash{"email": "jane@example.com", "name": "Jane"},
+	)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, new(activerecord.ErrRecordNotUnique)))
+	require.Nil(t, User.FindBy("email", "ann@example.com").Unwrap())
+
+	err = User.Where("age > ?", 20).UpdateAll(Hash{"name": "Adult"})
+	require.NoError(t, err)
+
+	adults, err := User.Where("name", "Adult").ToA()
+	require.NoError(t, err)
+	require.Len(t, adults, 2)
+
+	err = User.UpdateAll(Hash{"unknown": 1})
+	require.Error(t, err)
+
+	// Values are passed to the database as arguments of statements.
+	const injection = "O'Reilly', age = '99"
+	_, err = User.InsertAll(Hash{"email": "o'reilly@example.com", "name": "O'Reilly"})
+	require.NoError(t, err)
+
+	err = User.UpsertAll([]map[string]interface{}{
+		{"email": "o'reilly@example.com", "name": "Tim O'Reilly"},
+	}, activerecord.ConflictTarget("email"))
+	require.NoError(t, err)
+	require.Equal(t, "Tim O'Reilly", User.FindBy("email", "o'reilly@example.com").Unwrap().Attribute("name"))
+
+	err = User.Where("email", "o'reilly@example.com").UpdateAll(Hash{"name": injection})
+	require.NoError(t, err)
+
+	oreilly := User.FindBy("email", "o'reilly@example.com").Unwrap()
+	require.Equal(t, injection, oreilly.Attribute("name"))
+	require.Nil(t, oreilly.Attribute("age"))
+
+	_, err = oreilly.Delete()
+	require.NoError(t, err)
+
+	err = User.Where("name", "Adult").DeleteAll()
+	require.NoError(t, err)
+
+	all, err = User.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	err = User.All().DeleteAll()
+	require.NoError(t, err)
+
+	all, err = User.All().ToA()
+	require.NoError(t, err)
+	require.Len(t, all, 0)
+}
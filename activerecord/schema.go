@@ -0,0 +1,464 @@
+package activerecord
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFormat defines the format of the database schema dump.
+type SchemaFormat string
+
+const (
+	// SchemaGo is a Go source file of the "schema" package with Define function
+	// compatible with Migrate, and a list of applied migration Versions.
+	SchemaGo SchemaFormat = "go"
+
+	// SchemaSQL is a list of SQL statements, including versions of applied
+	// migrations.
+	SchemaSQL SchemaFormat = "sql"
+)
+
+// DumpSchema writes the schema of the primary database in the specified format.
+//
+//	f, _ := os.Create("schema/schema.go")
+//	err := activerecord.DumpSchema(f, activerecord.SchemaGo)
+func DumpSchema(w io.Writer, format SchemaFormat) error {
+	return defaultRegistry.DumpSchema(w, format)
+}
+
+// LoadSchema creates the database schema in one pass from the schema dumped
+// in Go format, and marks the specified migration versions as applied.
+//
+//	err := activerecord.LoadSchema(schema.Define, schema.Versions...)
+func LoadSchema(define func(*M), versions ...string) error {
+	return defaultRegistry.LoadSchema(define, versions...)
+}
+
+// LoadStructure creates the database schema in one pass from the schema dumped
+// in SQL format.
+func LoadStructure(r io.Reader) error {
+	return defaultRegistry.LoadStructure(r)
+}
+
+// DumpSchema writes the schema of the primary database of the registry.
+func (reg *Registry) DumpSchema(w io.Writer, format SchemaFormat) error {
+	// Replicas might be behind the writing database, read the actual state.
+	ctx := ConnectedTo(context.TODO(), RoleWriting)
+
+	conn, err := reg.connections.RetrieveConnection(primaryConnectionName)
+	if err != nil {
+		return err
+	}
+
+	tables, err := inspectTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	versions, err := schemaVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case SchemaGo:
+		return dumpGoSchema(w, tables, versions)
+	case SchemaSQL:
+		return dumpSQLSchema(DryRun(ctx, w), conn, tables, versions)
+	default:
+		return fmt.Errorf("unknown schema format %q", format)
+	}
+}
+
+// LoadSchema creates the database schema of the primary database of the registry.
+func (reg *Registry) LoadSchema(define func(*M), versions ...string) error {
+	m := newMigration("schema", reg.connections)
+	define(m)
+	return reg.loadSchema(context.TODO(), m, versions)
+}
+
+// LoadStructure creates the database schema of the primary database of the registry.
+func (reg *Registry) LoadStructure(r io.Reader) error {
+	m := newMigration("structure", reg.connections)
+
+	var (
+		stmt    strings.Builder
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		stmt.WriteString(line)
+		if !strings.HasSuffix(line, ";") {
+			stmt.WriteString("\n")
+			continue
+		}
+
+		m.Execute(strings.TrimSuffix(stmt.String(), ";"))
+		stmt.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if stmt.Len() != 0 {
+		m.Execute(stmt.String())
+	}
+
+	return reg.loadSchema(context.TODO(), m, nil)
+}
+
+func (reg *Registry) loadSchema(ctx context.Context, m *M, versions []string) error {
+	ctx = ContextWithSchemaChange(ctx)
+	return m.connections.Transaction(ctx, m.connectionName, func() error {
+		conn, err := m.connections.RetrieveConnection(m.connectionName)
+		if err != nil {
+			return err
+		}
+
+		for _, apply := range m.applyOperations() {
+			if err = apply(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if len(versions) == 0 {
+			return nil
+		}
+
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if applied == nil {
+			if err = createSchemaMigrations(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		for _, version := range versions {
+			if applied[version] {
+				continue
+			}
+			if err = insertVersion(ctx, conn, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// schemaVersions returns sorted versions of applied migrations.
+func schemaVersions(ctx context.Context, conn Conn) ([]string, error) {
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// inspectTables returns definitions of all tables of the database. Tables are
+// ordered, so referenced tables are defined before tables referencing them.
+func inspectTables(ctx context.Context, conn Conn) ([]*Table, error) {
+	tableNames, err := conn.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*Table, len(tableNames))
+	for _, tableName := range tableNames {
+		table := &Table{name: tableName}
+
+		columns, err := conn.ColumnDefinitions(ctx, tableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range columns {
+			if column.IsPrimaryKey {
+				table.PrimaryKey(column.Name)
+			}
+			table.columns = append(table.columns, column)
+		}
+
+		if table.indexes, err = conn.Indexes(ctx, tableName); err != nil {
+			return nil, err
+		}
+		if table.foreignKeys, err = conn.ForeignKeys(ctx, tableName); err != nil {
+			return nil, err
+		}
+		tables[tableName] = table
+	}
+
+	var (
+		sorted  = make([]*Table, 0, len(tables))
+		visited = make(map[string]bool, len(tables))
+		visit   func(tableName string)
+	)
+
+	visit = func(tableName string) {
+		table, ok := tables[tableName]
+		if !ok || visited[tableName] {
+			return
+		}
+		visited[tableName] = true
+
+		for _, fk := range table.foreignKeys {
+			visit(fk.ReferencedTable)
+		}
+		sorted = append(sorted, table)
+	}
+
+	for _, tableName := range tableNames {
+		visit(tableName)
+	}
+	return sorted, nil
+}
+
+func dumpSQLSchema(ctx context.Context, conn Conn, tables []*Table, versions []string) error {
+	for _, table := range tables {
+		if err := conn.CreateTable(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	for _, version := range versions {
+		stmt := fmt.Sprintf(`INSERT INTO %q ("version") VALUES ('%s')`,
+			SchemaMigrationsName, strings.ReplaceAll(version, "'", "''"))
+		if err := conn.Execute(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpGoSchema(w io.Writer, tables []*Table, versions []string) error {
+	var (
+		buf     bytes.Buffer
+		body    bytes.Buffer
+		imports = map[string]string{activerecordPkgPath: "activerecord"}
+	)
+
+	fmt.Fprintln(&body, "// Define creates the database schema.")
+	fmt.Fprintln(&body, "func Define(m *activerecord.M) {")
+	for _, table := range tables {
+		if table.Name() == SchemaMigrationsName {
+			continue
+		}
+		dumpGoTable(&body, table, imports)
+	}
+	fmt.Fprintln(&body, "}")
+
+	pkgPaths := make([]string, 0, len(imports))
+	for pkgPath := range imports {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+
+	fmt.Fprintln(&buf, "// Code generated by activerecord.DumpSchema. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package schema")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "import (")
+	for _, pkgPath := range pkgPaths {
+		if pkgName := imports[pkgPath]; pkgName != path.Base(pkgPath) {
+			fmt.Fprintf(&buf, "%s ", pkgName)
+		}
+		fmt.Fprintf(&buf, "%q\n", pkgPath)
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+
+	fmt.Fprintln(&buf, "// Versions of applied migrations.")
+	fmt.Fprintln(&buf, "var Versions = []string{")
+	for _, version := range versions {
+		fmt.Fprintf(&buf, "%q,\n", version)
+	}
+	fmt.Fprintln(&buf, "}")
+	fmt.Fprintln(&buf)
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// activerecordPkgPath is an import path of the package.
+var activerecordPkgPath = reflect.TypeOf(Table{}).PkgPath()
+
+func dumpGoTable(w io.Writer, table *Table, imports map[string]string) {
+	fmt.Fprintf(w, "m.CreateTable(%q, func(t *activerecord.Table) {\n", table.Name())
+
+	for _, column := range table.columns {
+		// The default primary key is created automatically.
+		_, isInt64 := column.Type.(*Int64)
+		if column.IsPrimaryKey && column.Name == defaultPrimaryKeyName && isInt64 {
+			continue
+		}
+
+		// Enums are restored with values, instead of the check constraint.
+		values, isEnum := enumColumn(column)
+		if isEnum {
+			column.Check = ""
+		}
+		options := columnOptionsGo(column)
+
+		switch column.Type.(type) {
+		case *Int64, *Float64, *String, *DateTime, *Decimal, *UUID, *Binary, *JSON, *Enum:
+			if isEnum {
+				fmt.Fprintf(w, "t.Enum(%q, []string{%s}%s)\n", column.Name, quoteGo(values), options)
+				break
+			}
+			if _, ok := column.Type.(*Enum); ok {
+				// Integer enums are defined by models, columns are integers.
+				fmt.Fprintf(w, "t.Int64(%q%s)\n", column.Name, options)
+				break
+			}
+			typeName := reflect.TypeOf(column.Type).Elem().Name()
+			fmt.Fprintf(w, "t.%s(%q%s)\n", typeName, column.Name, options)
+		default:
+			// Types registered by the application are defined in other packages.
+			typ := reflect.TypeOf(column.Type).Elem()
+			imports[typ.PkgPath()] = strings.SplitN(typ.String(), ".", 2)[0]
+
+			fmt.Fprintf(w, "t.DefineColumn(%q, new(%s)%s)\n", column.Name, typ, options)
+		}
+
+		if column.IsPrimaryKey {
+			fmt.Fprintf(w, "t.PrimaryKey(%q)\n", column.Name)
+		}
+	}
+
+	for _, fk := range table.foreignKeys {
+		fmt.Fprintf(w, "t.ForeignKey(%q%s)\n", fk.ReferencedTable, foreignKeyOptionsGo(fk))
+	}
+
+	for _, index := range table.indexes {
+		var options []string
+		if len(index.Columns) > 1 {
+			options = append(options, fmt.Sprintf(
+				"activerecord.Columns(%s)", quoteGo(index.Columns[1:])))
+		}
+		if index.Unique {
+			options = append(options, "activerecord.Unique()")
+		}
+		if index.Where != "" {
+			options = append(options, fmt.Sprintf("activerecord.Partial(%q)", index.Where))
+		}
+		if index.Name != newIndexDefinition(table.Name(), index.Columns[0], nil).Name &&
+			index.Name != newIndexDefinition(table.Name(), index.Columns[0], []IndexOption{
+				Columns(index.Columns[1:]...),
+			}).Name {
+			options = append(options, fmt.Sprintf("activerecord.IndexName(%q)", index.Name))
+		}
+
+		fmt.Fprintf(w, "t.Index(%q", index.Columns[0])
+		for _, option := range options {
+			fmt.Fprintf(w, ", %s", option)
+		}
+		fmt.Fprintln(w, ")")
+	}
+
+	fmt.Fprintln(w, "})")
+}
+
+// enumColumn returns values of the column defined with Table.Enum. Adapters
+// read such columns as strings restricted by the check constraint.
+func enumColumn(column ColumnDefinition) ([]string, bool) {
+	switch t := column.Type.(type) {
+	case *Enum:
+		return t.Values, !t.Integer
+	case *String:
+		return enumValues(column.Name, column.Check)
+	default:
+		return nil, false
+	}
+}
+
+// columnOptionsGo returns column options as a Go source code.
+func columnOptionsGo(column ColumnDefinition) string {
+	var buf strings.Builder
+	if column.NotNull {
+		buf.WriteString(", activerecord.NotNull()")
+	}
+	if column.Default != nil {
+		value, err := column.Type.Serialize(column.Default)
+		if err != nil {
+			value = column.Default
+		}
+		fmt.Fprintf(&buf, ", activerecord.Default(%#v)", value)
+	}
+	if column.DefaultSQL != "" {
+		fmt.Fprintf(&buf, ", activerecord.DefaultSQL(%q)", column.DefaultSQL)
+	}
+	if column.Limit != 0 {
+		fmt.Fprintf(&buf, ", activerecord.Limit(%d)", column.Limit)
+	}
+	if column.Precision != 0 {
+		fmt.Fprintf(&buf, ", activerecord.Precision(%d)", column.Precision)
+	}
+	if column.Scale != 0 {
+		fmt.Fprintf(&buf, ", activerecord.Scale(%d)", column.Scale)
+	}
+	if column.Check != "" {
+		fmt.Fprintf(&buf, ", activerecord.Check(%q)", column.Check)
+	}
+	return buf.String()
+}
+
+// foreignKeyOptionsGo returns options of the foreign key as a Go source code,
+// options matching defaults are omitted.
+func foreignKeyOptionsGo(fk ForeignKeyDefinition) string {
+	var (
+		buf      strings.Builder
+		defaults = newForeignKeyDefinition(fk.TableName, fk.ReferencedTable, nil)
+	)
+
+	if fk.Column != defaults.Column {
+		fmt.Fprintf(&buf, ", activerecord.ReferenceColumn(%q)", fk.Column)
+	}
+	if fk.PrimaryKey != defaults.PrimaryKey {
+		fmt.Fprintf(&buf, ", activerecord.ReferencePrimaryKey(%q)", fk.PrimaryKey)
+	}
+	if fk.Name != "" && fk.Name != newForeignKeyDefinition(
+		fk.TableName, fk.ReferencedTable, []ForeignKeyOption{ReferenceColumn(fk.Column)},
+	).Name {
+		fmt.Fprintf(&buf, ", activerecord.ConstraintName(%q)", fk.Name)
+	}
+
+	actions := map[ReferentialAction]string{
+		Cascade: "Cascade", Nullify: "Nullify", Restrict: "Restrict",
+	}
+	if action, ok := actions[fk.OnDelete]; ok {
+		fmt.Fprintf(&buf, ", activerecord.OnDelete(activerecord.%s)", action)
+	}
+	if action, ok := actions[fk.OnUpdate]; ok {
+		fmt.Fprintf(&buf, ", activerecord.OnUpdate(activerecord.%s)", action)
+	}
+	return buf.String()
+}
+
+func quoteGo(ss []string) string {
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, fmt.Sprintf("%q", s))
+	}
+	return strings.Join(quoted, ", ")
+}
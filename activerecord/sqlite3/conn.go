@@ -4,14 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/activegraph/activegraph/activerecord"
 	"github.com/activegraph/activegraph/activerecord/ansi"
+	"github.com/activegraph/activegraph/activesupport"
 	"github.com/mattn/go-sqlite3"
 )
 
+// driverName is the name of the SQLite driver with collations used by the
+// adapter.
+const driverName = "sqlite3_activerecord"
+
 func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterCollation(decimalCollation, compareDecimals)
+		},
+	})
 	activerecord.RegisterConnectionAdapter("sqlite3", Connect)
 }
 
@@ -22,29 +34,83 @@ type Conn struct {
 
 	db *sql.DB
 	tx *sql.Tx
+
+	// conn is a dedicated connection of the immediate transaction.
+	conn *sql.Conn
+
+	// immediate is true for the immediate transaction, which locks the whole
+	// database for writing at the beginning.
+	immediate bool
+
+	// noForeignKeys is true for the schema transaction, where foreign keys
+	// are disabled for the dedicated connection and checked on commit.
+	noForeignKeys bool
+}
+
+// dataSourceName returns a database file name with connection parameters, so
+// each connection of the pool is configured in the same way.
+func dataSourceName(conf activerecord.DatabaseConfig) string {
+	params := make(url.Values)
+
+	// Enable foreign keys support.
+	params.Set("_foreign_keys", "on")
+
+	if conf.BusyTimeout != 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(conf.BusyTimeout.Milliseconds(), 10))
+	}
+	if conf.JournalMode != "" {
+		params.Set("_journal_mode", conf.JournalMode)
+	}
+
+	sep := "?"
+	if strings.Contains(conf.Database, "?") {
+		sep = "&"
+	}
+	return conf.Database + sep + params.Encode()
 }
 
 func Connect(conf activerecord.DatabaseConfig) (activerecord.Conn, error) {
-	db, err := sql.Open("sqlite3", conf.Database)
+	db, err := sql.Open(driverName, dataSourceName(conf))
 	if err != nil {
 		return nil, err
 	}
+
+	ansi.ConfigurePool(db, conf)
+
+	// Open the connection to ensure the configuration is valid.
+	if err = db.PingContext(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	conn := &Conn{
 		db:                   db,
 		ConnectionStatements: db,
-		SchemaStatements:     ansi.SchemaStatements{db},
-		DatabaseStatements:   ansi.DatabaseStatements{db},
-	}
-
-	// Enable foreign keys support.
-	_, err = db.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
-	if err != nil {
-		return nil, err
+		SchemaStatements:     ansi.SchemaStatements{Conn: db},
+		DatabaseStatements:   ansi.DatabaseStatements{Conn: db},
 	}
 	return conn, nil
 }
 
+func (c *Conn) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+func (c *Conn) Stats() sql.DBStats {
+	return c.db.Stats()
+}
+
 func (c *Conn) Close() error {
+	if c.conn != nil {
+		// Ensure the connection is returned to the pool without an open
+		// transaction, the error is ignored, since the transaction might be
+		// already finished.
+		c.conn.ExecContext(context.Background(), "ROLLBACK")
+		if c.noForeignKeys {
+			c.conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+		}
+		return c.conn.Close()
+	}
 	if c.tx != nil {
 		return c.tx.Commit()
 	}
@@ -52,6 +118,15 @@ func (c *Conn) Close() error {
 }
 
 func (c *Conn) BeginTransaction(ctx context.Context) (activerecord.Conn, error) {
+	// SQLite does not support row-level locks, therefore the database is
+	// locked for writing at the beginning of the locking transaction.
+	if activerecord.LockFromContext(ctx) != "" {
+		return c.beginImmediateTransaction(ctx)
+	}
+	if activerecord.SchemaChangeFromContext(ctx) {
+		return c.beginSchemaTransaction(ctx)
+	}
+
 	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -63,12 +138,99 @@ func (c *Conn) BeginTransaction(ctx context.Context) (activerecord.Conn, error)
 		db:                   c.db,
 		tx:                   tx,
 		ConnectionStatements: tx,
-		SchemaStatements:     ansi.SchemaStatements{tx},
-		DatabaseStatements:   ansi.DatabaseStatements{tx},
+		SchemaStatements:     ansi.SchemaStatements{Conn: tx},
+		DatabaseStatements:   ansi.DatabaseStatements{Conn: tx},
 	}, nil
 }
 
+func (c *Conn) beginImmediateTransaction(ctx context.Context) (activerecord.Conn, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	fmt.Println("BEGIN IMMEDIATE TRANSACTION")
+
+	return &Conn{
+		db:                   c.db,
+		conn:                 conn,
+		immediate:            true,
+		ConnectionStatements: conn,
+		SchemaStatements:     ansi.SchemaStatements{Conn: conn},
+		DatabaseStatements:   ansi.DatabaseStatements{Conn: conn},
+	}, nil
+}
+
+// beginSchemaTransaction starts the transaction with disabled foreign keys, so
+// tables could be re-created without triggering actions of foreign keys that
+// reference them. Foreign keys could not be disabled within the transaction,
+// therefore they are disabled for the dedicated connection before it begins.
+func (c *Conn) beginSchemaTransaction(ctx context.Context) (activerecord.Conn, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "BEGIN"); err != nil {
+		conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		conn.Close()
+		return nil, err
+	}
+
+	fmt.Println("BEGIN TRANSACTION")
+
+	return &Conn{
+		db:                   c.db,
+		conn:                 conn,
+		noForeignKeys:        true,
+		ConnectionStatements: conn,
+		SchemaStatements:     ansi.SchemaStatements{Conn: conn},
+		DatabaseStatements:   ansi.DatabaseStatements{Conn: conn},
+	}, nil
+}
+
+// checkForeignKeys returns an error, when rows of the database violate foreign
+// key constraints.
+func (c *Conn) checkForeignKeys(ctx context.Context) error {
+	rws, err := c.ConnectionStatements.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+
+	defer rws.Close()
+
+	if rws.Next() {
+		var (
+			tableName, parent string
+			rowid             sql.NullInt64
+			fkid              int
+		)
+		if err = rws.Scan(&tableName, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key of %q referencing %q is violated", tableName, parent)
+	}
+	return rws.Err()
+}
+
 func (c *Conn) CommitTransaction(ctx context.Context) error {
+	if c.noForeignKeys {
+		if err := c.checkForeignKeys(ctx); err != nil {
+			return err
+		}
+	}
+	if c.conn != nil {
+		fmt.Println("COMMIT TRANSACTION")
+		_, err := c.conn.ExecContext(ctx, "COMMIT")
+		return err
+	}
 	if c.tx == nil {
 		return fmt.Errorf("no transaction is open")
 	}
@@ -77,6 +239,11 @@ func (c *Conn) CommitTransaction(ctx context.Context) error {
 }
 
 func (c *Conn) RollbackTransaction(ctx context.Context) error {
+	if c.conn != nil {
+		fmt.Println("ROLLBACK TRANSACTION")
+		_, err := c.conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
 	if c.tx == nil {
 		return fmt.Errorf("no transaction is open")
 	}
@@ -105,9 +272,58 @@ func (c *Conn) ExecInsert(ctx context.Context, op *activerecord.InsertOperation)
 	return id, err
 }
 
+func (c *Conn) ExecUpdate(ctx context.Context, op *activerecord.UpdateOperation) error {
+	err := c.DatabaseStatements.ExecUpdate(ctx, op)
+	if err, ok := err.(sqlite3.Error); ok {
+		switch err.ExtendedCode {
+		case sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintUnique:
+			return &activerecord.ErrRecordNotUnique{Err: err}
+		}
+	}
+	return err
+}
+
+func (c *Conn) ExecQuery(
+	ctx context.Context, op *activerecord.QueryOperation, cb func(activesupport.Hash) bool,
+) error {
+	// SQLite does not support row-level locks, the locking clause is omitted,
+	// since the whole database is locked by the immediate transaction. Locking
+	// queries outside of the immediate transaction would silently lock nothing.
+	if op.Lock != "" {
+		if !c.immediate {
+			return &activerecord.ErrUnsupportedLock{
+				Mode:        op.Lock,
+				Description: "transaction must be started with activerecord.ContextWithLock",
+			}
+		}
+		newop := *op
+		newop.Lock = ""
+		op = &newop
+	}
+	return c.DatabaseStatements.ExecQuery(ctx, op, cb)
+}
+
+// JSONExtract returns the expression of the value at the path of keys within
+// the JSON column, the value is extracted with json_extract function.
+func (c *Conn) JSONExtract(column string, path ...string) string {
+	return fmt.Sprintf("json_extract(%q, %s)", column, ansi.JSONPath(path...))
+}
+
+func (c *Conn) ColumnType(typeName string) (activerecord.Type, error) {
+	if strings.EqualFold(typeName, decimalType) {
+		return new(activerecord.Decimal), nil
+	}
+	return c.SchemaStatements.ColumnType(typeName)
+}
+
 func (c *Conn) ColumnDefinitions(ctx context.Context, tableName string) (
 	[]activerecord.ColumnDefinition, error,
 ) {
+	checks, err := c.checkConstraints(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
 	stmt := fmt.Sprintf("PRAGMA table_info('%s')", tableName)
 	rws, err := c.ConnectionStatements.QueryContext(ctx, stmt)
 	if err != nil {
@@ -121,7 +337,7 @@ func (c *Conn) ColumnDefinitions(ctx context.Context, tableName string) (
 		var (
 			cid, notnull, pk int
 			fname, ftype     string
-			defaultValue     interface{}
+			defaultValue     sql.NullString
 		)
 
 		err := rws.Scan(&cid, &fname, &ftype, &notnull, &defaultValue, &pk)
@@ -129,17 +345,34 @@ func (c *Conn) ColumnDefinitions(ctx context.Context, tableName string) (
 			return nil, err
 		}
 
-		columnType, err := c.ColumnType(ftype)
+		typeName, params := parseColumnType(ftype)
+		columnType, err := c.ColumnType(typeName)
 		if err != nil {
 			return nil, err
 		}
 
-		definitions = append(definitions, activerecord.ColumnDefinition{
+		column := activerecord.ColumnDefinition{
 			Name:         fname,
 			Type:         columnType,
 			NotNull:      notnull == 1,
 			IsPrimaryKey: pk == 1,
-		})
+			Check:        checks[fname],
+		}
+
+		switch {
+		case len(params) == 1 && typeName == "varchar":
+			column.Limit = params[0]
+		case len(params) == 1:
+			column.Precision = params[0]
+		case len(params) == 2:
+			column.Precision, column.Scale = params[0], params[1]
+		}
+
+		if defaultValue.Valid {
+			column.Default, column.DefaultSQL = parseDefault(columnType, defaultValue.String)
+		}
+
+		definitions = append(definitions, column)
 	}
 	if len(definitions) == 0 {
 		return nil, activerecord.ErrTableNotExist{TableName: tableName}
@@ -147,60 +380,398 @@ func (c *Conn) ColumnDefinitions(ctx context.Context, tableName string) (
 	return definitions, nil
 }
 
-func (c *Conn) AddForeignKey(ctx context.Context, owner, target string) error {
-	// SQLite does not support adding a foreign key constraint, which
-	// is implemented in ANSI schema statements, therefore we need to
-	// drop table and create a new one with foreign keys inside.
-	stmts := []string{
-		`PRAGMA foreign_keys = OFF`,
-		fmt.Sprintf(`ALTER TABLE %q RENAME TO "temp_%s"`, owner, owner),
+// Indexes returns indexes of the table created with CREATE INDEX statement,
+// indexes implicitly created for primary keys and unique constraints are
+// omitted.
+func (c *Conn) Indexes(ctx context.Context, tableName string) (
+	[]activerecord.IndexDefinition, error,
+) {
+	const stmt = `SELECT "name", "sql" FROM "sqlite_master" ` +
+		`WHERE "type" = 'index' AND "tbl_name" = ? AND "sql" IS NOT NULL`
+	rws, err := c.ConnectionStatements.QueryContext(ctx, stmt, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rws.Close()
+
+	var (
+		indexes []activerecord.IndexDefinition
+		// terms are indexed terms of statements used to create indexes.
+		terms [][]string
+	)
+	for rws.Next() {
+		var name, sql string
+		if err := rws.Scan(&name, &sql); err != nil {
+			return nil, err
+		}
+
+		index := activerecord.IndexDefinition{Name: name, TableName: tableName}
+		index.Unique = strings.HasPrefix(strings.ToUpper(sql), "CREATE UNIQUE")
+		terms = append(terms, indexTerms(sql))
+
+		// SQLite does not expose the condition of the partial index, so
+		// extract it from the statement used to create the index.
+		if pos := strings.LastIndex(strings.ToUpper(sql), " WHERE "); pos != -1 {
+			index.Where = strings.TrimSpace(sql[pos+len(" WHERE "):])
+		}
+		indexes = append(indexes, index)
+	}
+	if err = rws.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		stmt := fmt.Sprintf("PRAGMA index_info('%s')", indexes[i].Name)
+		rws, err := c.ConnectionStatements.QueryContext(ctx, stmt)
+		if err != nil {
+			return nil, err
+		}
+
+		for rws.Next() {
+			var (
+				seqno, cid int
+				name       sql.NullString
+			)
+			if err := rws.Scan(&seqno, &cid, &name); err != nil {
+				rws.Close()
+				return nil, err
+			}
+
+			// SQLite does not expose names of indexed expressions, so take
+			// the expression from the statement used to create the index.
+			column := name.String
+			if !name.Valid && seqno < len(terms[i]) {
+				column = terms[i][seqno]
+				if !strings.HasPrefix(column, "(") {
+					column = "(" + column + ")"
+				}
+			}
+			indexes[i].Columns = append(indexes[i].Columns, column)
+		}
+		rws.Close()
+	}
+	return indexes, nil
+}
+
+// RemoveIndex removes the index of the table. Names of indexes are unique within
+// the whole database, so the index of another table is never removed.
+func (c *Conn) RemoveIndex(ctx context.Context, tableName, indexName string) error {
+	if activerecord.DryRunWriter(ctx) == nil {
+		const stmt = `SELECT "name" FROM "sqlite_master" ` +
+			`WHERE "type" = 'index' AND "name" = ? AND "tbl_name" = ?`
+		rws, err := c.ConnectionStatements.QueryContext(ctx, stmt, indexName, tableName)
+		if err != nil {
+			return err
+		}
+
+		exists := rws.Next()
+		err = rws.Err()
+		rws.Close()
+
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("index %q of table %q does not exist", indexName, tableName)
+		}
 	}
+	return c.SchemaStatements.RemoveIndex(ctx, tableName, indexName)
+}
 
-	columns, err := c.ColumnDefinitions(ctx, owner)
+func (c *Conn) ForeignKeys(ctx context.Context, tableName string) (
+	[]activerecord.ForeignKeyDefinition, error,
+) {
+	names, err := c.foreignKeyNames(ctx, tableName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, stmt := range stmts {
-		if _, err := c.ExecContext(ctx, stmt); err != nil {
+	stmt := fmt.Sprintf("PRAGMA foreign_key_list('%s')", tableName)
+	rws, err := c.ConnectionStatements.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rws.Close()
+
+	var fks []activerecord.ForeignKeyDefinition
+	for rws.Next() {
+		var (
+			id, seq int
+			fk      = activerecord.ForeignKeyDefinition{TableName: tableName}
+			to      sql.NullString
+			match   string
+		)
+
+		err := rws.Scan(
+			&id, &seq, &fk.ReferencedTable, &fk.Column, &to, &fk.OnUpdate, &fk.OnDelete, &match,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// When the referenced column is omitted, the primary key is used.
+		fk.PrimaryKey = "id"
+		if to.Valid {
+			fk.PrimaryKey = to.String
+		}
+		fk.Name = names[fk.Column]
+		fks = append(fks, fk)
+	}
+	return fks, rws.Err()
+}
+
+func (c *Conn) Tables(ctx context.Context) ([]string, error) {
+	const stmt = `SELECT "name" FROM "sqlite_master" ` +
+		`WHERE "type" = 'table' AND "name" NOT LIKE 'sqlite_%' ORDER BY "name"`
+
+	rws, err := c.ConnectionStatements.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rws.Close()
+
+	var tables []string
+	for rws.Next() {
+		var name string
+		if err := rws.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rws.Err()
+}
+
+// rebuildTable alters the table by creating a new table with altered columns
+// and foreign keys, copying the data into it and replacing the original table.
+// SQLite supports only a limited set of ALTER TABLE statements, so the rest of
+// alterations are implemented through the rebuild.
+//
+// The rebuild follows the procedure recommended by SQLite, it requires foreign
+// keys to be disabled, otherwise the drop of the original table deletes rows
+// of tables referencing it, therefore the table could be rebuilt only within
+// the schema transaction.
+func (c *Conn) rebuildTable(
+	ctx context.Context,
+	tableName string,
+	alter func([]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition) (
+		[]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition,
+	),
+) error {
+	// The rebuild must be atomic, start a new transaction, when the connection
+	// is not a part of the transaction yet.
+	if c.tx == nil && c.conn == nil {
+		conn, err := c.BeginTransaction(activerecord.ContextWithSchemaChange(ctx))
+		if err != nil {
 			return err
 		}
+
+		defer conn.Close()
+
+		if err = conn.(*Conn).rebuildTable(ctx, tableName, alter); err != nil {
+			conn.RollbackTransaction(ctx)
+			return err
+		}
+		return conn.CommitTransaction(ctx)
+	}
+	if !c.noForeignKeys {
+		return fmt.Errorf("table %q could not be rebuilt within the transaction "+
+			"with enabled foreign keys", tableName)
 	}
 
-	var buf strings.Builder
-	fmt.Fprintf(&buf, `CREATE TABLE %q (`, owner)
+	columns, err := c.ColumnDefinitions(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	fks, err := c.ForeignKeys(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	indexes, err := c.Indexes(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	oldColumns := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		oldColumns[column.Name] = struct{}{}
+	}
 
-	var primaryKey string
+	columns, fks = alter(columns, fks)
 
+	var (
+		columnNames []string
+		newTable    = "new_" + tableName
+	)
+
+	// Copy only columns that exist in the original table.
 	for _, column := range columns {
-		nativeType := column.Type.NativeType()
-		if column.NotNull {
-			nativeType += " NOT NULL"
-		}
-		if column.IsPrimaryKey {
-			primaryKey = column.Name
+		if _, ok := oldColumns[column.Name]; ok {
+			columnNames = append(columnNames, strconv.Quote(column.Name))
 		}
-		fmt.Fprintf(&buf, `%s %s, `, column.Name, nativeType)
 	}
 
-	// TODO: Add all foreign keys as well.
-	fk := fmt.Sprintf("%s_id", strings.TrimSuffix(target, "s"))
+	copyColumns := strings.Join(columnNames, ", ")
 
-	fmt.Fprintf(&buf, `FOREIGN KEY (%q) REFERENCES "%s" ("id"), `, fk, target)
-	fmt.Fprintf(&buf, `PRIMARY KEY (%q))`, primaryKey)
+	// Foreign keys are checked on commit of the schema transaction.
+	stmts := []string{
+		createTableSQL(newTable, columns, fks),
+		fmt.Sprintf(`INSERT INTO %q (%s) SELECT %s FROM %q`,
+			newTable, copyColumns, copyColumns, tableName),
+		fmt.Sprintf(`DROP TABLE %q`, tableName),
+		fmt.Sprintf(`ALTER TABLE %q RENAME TO %q`, newTable, tableName),
+	}
 
-	stmts = []string{
-		buf.String(), // CREATE TABLE ...
-		fmt.Sprintf(`INSERT INTO %q SELECT * FROM "temp_%s"`, owner, owner),
-		fmt.Sprintf(`DROP TABLE "temp_%s"`, owner),
-		fmt.Sprintf(`PRAGMA foreign_keys = ON`),
+	// Indexes are dropped along with the table, restore indexes, which
+	// columns are still present in the table.
+	newColumns := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		newColumns[column.Name] = struct{}{}
+	}
+
+	var removedColumns []string
+	for name := range oldColumns {
+		if _, ok := newColumns[name]; !ok {
+			removedColumns = append(removedColumns, name)
+		}
+	}
+
+indexes:
+	for _, index := range indexes {
+		for _, column := range index.Columns {
+			// Expressions are restored, unless they refer removed columns.
+			if strings.HasPrefix(column, "(") {
+				for _, name := range removedColumns {
+					if refersColumn(column, name) {
+						continue indexes
+					}
+				}
+				continue
+			}
+			if _, ok := newColumns[column]; !ok {
+				continue indexes
+			}
+		}
+		stmts = append(stmts, ansi.IndexSQL(index))
 	}
 
 	for _, stmt := range stmts {
-		if _, err := c.ExecContext(ctx, stmt); err != nil {
+		if _, err := ansi.Exec(ctx, c.ConnectionStatements, stmt); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+func (c *Conn) CreateTable(ctx context.Context, table *activerecord.Table) error {
+	stmt := createTableSQL(table.Name(), table.Columns(), table.ForeignKeys())
+	if _, err := ansi.Exec(ctx, c.ConnectionStatements, stmt); err != nil {
+		return err
+	}
+
+	for _, index := range table.Indexes() {
+		if err := c.AddIndex(ctx, index); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (c *Conn) AddColumn(
+	ctx context.Context, tableName string, column activerecord.ColumnDefinition,
+) error {
+	const stmt = `ALTER TABLE %q ADD COLUMN %s`
+	_, err := ansi.Exec(ctx, c.SchemaStatements.Conn, fmt.Sprintf(stmt, tableName, columnSQL(column)))
+	return err
+}
+
+func (c *Conn) RemoveColumn(ctx context.Context, tableName, columnName string) error {
+	return c.rebuildTable(ctx, tableName, func(
+		columns []activerecord.ColumnDefinition, fks []activerecord.ForeignKeyDefinition,
+	) (
+		[]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition,
+	) {
+		newColumns := make([]activerecord.ColumnDefinition, 0, len(columns))
+		for _, column := range columns {
+			if column.Name != columnName {
+				newColumns = append(newColumns, column)
+			}
+		}
+		newFks := make([]activerecord.ForeignKeyDefinition, 0, len(fks))
+		for _, fk := range fks {
+			if fk.Column != columnName {
+				newFks = append(newFks, fk)
+			}
+		}
+		return newColumns, newFks
+	})
+}
+
+func (c *Conn) ChangeColumn(
+	ctx context.Context, tableName string, newColumn activerecord.ColumnDefinition,
+) error {
+	return c.rebuildTable(ctx, tableName, func(
+		columns []activerecord.ColumnDefinition, fks []activerecord.ForeignKeyDefinition,
+	) (
+		[]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition,
+	) {
+		for i, column := range columns {
+			if column.Name == newColumn.Name {
+				newColumn.IsPrimaryKey = column.IsPrimaryKey
+				columns[i] = newColumn
+			}
+		}
+		return columns, fks
+	})
+}
+
+func (c *Conn) AddForeignKey(ctx context.Context, fk activerecord.ForeignKeyDefinition) error {
+	// SQLite does not support adding a foreign key constraint, which
+	// is implemented in ANSI schema statements, therefore we need to
+	// rebuild the table with foreign keys inside.
+	return c.rebuildTable(ctx, fk.TableName, func(
+		columns []activerecord.ColumnDefinition, fks []activerecord.ForeignKeyDefinition,
+	) (
+		[]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition,
+	) {
+		return columns, append(fks, fk)
+	})
+}
+
+func (c *Conn) RemoveForeignKey(ctx context.Context, fk activerecord.ForeignKeyDefinition) error {
+	// Constraints created without a name are identified by the referencing
+	// column and the referenced table.
+	matches := func(oldFk activerecord.ForeignKeyDefinition) bool {
+		return oldFk.Column == fk.Column && oldFk.ReferencedTable == fk.ReferencedTable
+	}
+
+	fks, err := c.ForeignKeys(ctx, fk.TableName)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	for _, oldFk := range fks {
+		exists = exists || matches(oldFk)
+	}
+	if !exists {
+		return fmt.Errorf("foreign key %q of %q referencing %q does not exist",
+			fk.Column, fk.TableName, fk.ReferencedTable)
+	}
+
+	return c.rebuildTable(ctx, fk.TableName, func(
+		columns []activerecord.ColumnDefinition, fks []activerecord.ForeignKeyDefinition,
+	) (
+		[]activerecord.ColumnDefinition, []activerecord.ForeignKeyDefinition,
+	) {
+		newFks := make([]activerecord.ForeignKeyDefinition, 0, len(fks))
+		for _, oldFk := range fks {
+			if !matches(oldFk) {
+				newFks = append(newFks, oldFk)
+			}
+		}
+		return columns, newFks
+	})
+}
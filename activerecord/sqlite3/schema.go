@@ -0,0 +1,362 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/activegraph/activegraph/activerecord"
+	"github.com/activegraph/activegraph/activerecord/ansi"
+	"github.com/activegraph/activegraph/activesupport"
+)
+
+// decimalType is the declared type of decimal columns. SQLite converts values of
+// columns with NUMERIC affinity into floating point numbers, which keep only 15
+// significant digits, therefore decimal numbers are stored in columns of TEXT
+// affinity and compared with the decimal collation.
+const decimalType = "DECIMAL_TEXT"
+
+// decimalCollation is the collating sequence of decimal columns. Values of TEXT
+// affinity are compared as strings (e.g. "10.00" < "9.50"), the collation
+// compares them as numbers, so ordering, range predicates and MIN/MAX
+// aggregates of decimal columns work as expected.
+const decimalCollation = "DECIMAL"
+
+// compareDecimals is a comparison function of the decimal collation. Values
+// that are not decimal numbers are compared as strings.
+func compareDecimals(a, b string) int {
+	x, err := activesupport.ParseBigDecimal(a)
+	if err != nil {
+		return strings.Compare(a, b)
+	}
+	y, err := activesupport.ParseBigDecimal(b)
+	if err != nil {
+		return strings.Compare(a, b)
+	}
+	return x.Cmp(y)
+}
+
+// decimalText is a decimal type declared with the type of TEXT affinity.
+type decimalText struct {
+	*activerecord.Decimal
+}
+
+func (decimalText) NativeType() string { return decimalType }
+
+// nativeColumn returns the column definition with the type declared by SQLite.
+func nativeColumn(column activerecord.ColumnDefinition) activerecord.ColumnDefinition {
+	if decimal, ok := column.Type.(*activerecord.Decimal); ok {
+		column.Type = decimalText{decimal}
+	}
+	return column
+}
+
+// columnSQL returns the column definition with the type declared by SQLite, the
+// decimal columns are declared with the decimal collation.
+func columnSQL(column activerecord.ColumnDefinition) string {
+	column = nativeColumn(column)
+	if _, ok := column.Type.(decimalText); !ok {
+		return ansi.ColumnSQL(column)
+	}
+
+	def := fmt.Sprintf(`%q %s`, column.Name, ansi.ColumnTypeSQL(column))
+	return def + " COLLATE " + decimalCollation + strings.TrimPrefix(ansi.ColumnSQL(column), def)
+}
+
+// createTableSQL returns the statement creating the table with given columns
+// and foreign keys.
+func createTableSQL(
+	tableName string,
+	columns []activerecord.ColumnDefinition,
+	fks []activerecord.ForeignKeyDefinition,
+) string {
+	var (
+		buf        strings.Builder
+		primaryKey string
+	)
+
+	fmt.Fprintf(&buf, `CREATE TABLE %q (`, tableName)
+	for _, column := range columns {
+		if column.IsPrimaryKey {
+			primaryKey = column.Name
+		}
+		fmt.Fprintf(&buf, `%s, `, columnSQL(column))
+	}
+	for _, fk := range fks {
+		fmt.Fprintf(&buf, `%s, `, ansi.ForeignKeySQL(fk))
+	}
+	fmt.Fprintf(&buf, `PRIMARY KEY (%q))`, primaryKey)
+	return buf.String()
+}
+
+// indexTerms returns indexed columns and expressions of the statement used to
+// create the index, e.g. "CREATE INDEX i ON t (a, lower(b))" -> ["a", "lower(b)"].
+func indexTerms(stmt string) []string {
+	pos := strings.Index(strings.ToUpper(stmt), " ON ")
+	if pos == -1 {
+		return nil
+	}
+	start := strings.Index(stmt[pos:], "(")
+	if start == -1 {
+		return nil
+	}
+
+	var (
+		terms []string
+		depth int
+		quote byte
+		term  = pos + start + 1
+	)
+	for i := term; i < len(stmt); i++ {
+		switch c := stmt[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ')' || (c == ',' && depth == 0):
+			terms = append(terms, strings.TrimSpace(stmt[term:i]))
+			if c == ')' {
+				return terms
+			}
+			term = i + 1
+		}
+	}
+	return nil
+}
+
+// refersColumn returns true, when the expression refers the column by name.
+func refersColumn(expr, columnName string) bool {
+	re := regexp.MustCompile(`(^|[^\w])"?` + regexp.QuoteMeta(columnName) + `"?([^\w]|$)`)
+	return re.MatchString(expr)
+}
+
+// parseColumnType splits the declared type of the column into the lower-case
+// type name and numeric parameters, e.g. "DECIMAL(10,2)" -> "decimal", [10, 2].
+func parseColumnType(declType string) (typeName string, params []int) {
+	typeName = strings.ToLower(strings.TrimSpace(declType))
+
+	pos := strings.Index(typeName, "(")
+	if pos == -1 || !strings.HasSuffix(typeName, ")") {
+		return typeName, nil
+	}
+
+	for _, param := range strings.Split(typeName[pos+1:len(typeName)-1], ",") {
+		num, err := strconv.Atoi(strings.TrimSpace(param))
+		if err != nil {
+			return typeName, nil
+		}
+		params = append(params, num)
+	}
+	return strings.TrimSpace(typeName[:pos]), params
+}
+
+// parseDefault parses the default value of the column as it is stored by
+// SQLite. Literals are converted to values of the column type, the rest of
+// expressions are returned as is.
+func parseDefault(columnType activerecord.Type, value string) (
+	literal interface{}, expr string,
+) {
+	var parsed interface{}
+
+	switch {
+	case strings.EqualFold(value, "NULL"):
+		return nil, ""
+	case strings.EqualFold(value, "TRUE"):
+		parsed = true
+	case strings.EqualFold(value, "FALSE"):
+		parsed = false
+	case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+		parsed = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	case strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")"):
+		return nil, value[1 : len(value)-1]
+	default:
+		if num, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsed = num
+		} else if num, err := strconv.ParseFloat(value, 64); err == nil {
+			parsed = num
+		} else {
+			return nil, value
+		}
+	}
+
+	if literal, err := columnType.Deserialize(parsed); err == nil {
+		return literal, ""
+	}
+	return parsed, ""
+}
+
+// checkConstraints returns check constraints of the table columns. SQLite does
+// not expose check constraints, so they are extracted from the statement used
+// to create the table.
+func (c *Conn) checkConstraints(ctx context.Context, tableName string) (
+	map[string]string, error,
+) {
+	defs, err := c.tableDefinitions(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make(map[string]string)
+	for _, def := range defs {
+		name, rest := definitionName(def)
+		if cond := checkCondition(rest); name != "" && cond != "" {
+			checks[name] = cond
+		}
+	}
+	return checks, nil
+}
+
+// foreignKeyNames returns names of foreign key constraints by the referencing
+// column. SQLite does not expose names of constraints, so they are extracted
+// from the statement used to create the table.
+func (c *Conn) foreignKeyNames(ctx context.Context, tableName string) (
+	map[string]string, error,
+) {
+	defs, err := c.tableDefinitions(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for _, def := range defs {
+		if !strings.HasPrefix(strings.ToUpper(def), "CONSTRAINT") {
+			continue
+		}
+
+		name, rest := definitionName(strings.TrimSpace(def[len("CONSTRAINT"):]))
+		rest = strings.TrimSpace(rest)
+		if name == "" || !strings.HasPrefix(strings.ToUpper(rest), "FOREIGN") {
+			continue
+		}
+
+		start := strings.Index(rest, "(")
+		end := strings.Index(rest, ")")
+		if start == -1 || end <= start {
+			continue
+		}
+		column := strings.Trim(strings.TrimSpace(rest[start+1:end]), "\"`")
+		names[column] = name
+	}
+	return names, nil
+}
+
+// tableDefinitions returns column definitions and table constraints from the
+// statement used to create the table.
+func (c *Conn) tableDefinitions(ctx context.Context, tableName string) ([]string, error) {
+	const stmt = `SELECT "sql" FROM "sqlite_master" WHERE "type" = 'table' AND "name" = ?`
+
+	var createStmt sql.NullString
+
+	rws, err := c.ConnectionStatements.QueryContext(ctx, stmt, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rws.Close()
+
+	if rws.Next() {
+		if err = rws.Scan(&createStmt); err != nil {
+			return nil, err
+		}
+	}
+	if err = rws.Err(); err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(createStmt.String, "(")
+	end := strings.LastIndex(createStmt.String, ")")
+	if start == -1 || end <= start {
+		return nil, nil
+	}
+	return splitDefinitions(createStmt.String[start+1 : end]), nil
+}
+
+// splitDefinitions splits the body of CREATE TABLE statement by top-level
+// commas, so each element is either a column definition or a table constraint.
+func splitDefinitions(body string) []string {
+	var (
+		defs  []string
+		depth int
+		quote rune
+		start int
+	)
+
+	for i, ch := range body {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			defs = append(defs, strings.TrimSpace(body[start:i]))
+			start = i + 1
+		}
+	}
+	return append(defs, strings.TrimSpace(body[start:]))
+}
+
+// definitionName returns the name of the column and the rest of the column
+// definition. Table constraints have no name.
+func definitionName(def string) (name, rest string) {
+	if def == "" {
+		return "", ""
+	}
+
+	if quote := def[0]; quote == '"' || quote == '`' {
+		end := strings.IndexByte(def[1:], quote)
+		if end == -1 {
+			return "", ""
+		}
+		return def[1 : end+1], def[end+2:]
+	}
+
+	fields := strings.Fields(def)
+	switch strings.ToUpper(fields[0]) {
+	case "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "CONSTRAINT":
+		return "", ""
+	}
+	return fields[0], strings.TrimPrefix(def, fields[0])
+}
+
+// checkCondition returns the condition of the CHECK constraint within the
+// column definition.
+func checkCondition(def string) string {
+	pos := strings.Index(strings.ToUpper(def), "CHECK")
+	if pos == -1 {
+		return ""
+	}
+
+	def = strings.TrimSpace(def[pos+len("CHECK"):])
+	if !strings.HasPrefix(def, "(") {
+		return ""
+	}
+
+	var depth int
+	for i, ch := range def {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return def[1:i]
+			}
+		}
+	}
+	return ""
+}
@@ -1,8 +1,12 @@
 package activerecord
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/activegraph/activegraph/activesupport"
@@ -34,6 +38,44 @@ func (e ErrUnsupportedType) Error() string {
 	return fmt.Sprintf("unsupported type '%s'", e.TypeName)
 }
 
+var registeredTypes = struct {
+	sync.RWMutex
+	types map[string]Type
+}{types: make(map[string]Type)}
+
+// RegisterType registers the type under the native type names, so columns of
+// these types are read from the database as values of the registered type.
+// By default the type is registered under its native type.
+//
+//	activerecord.RegisterType(new(EmailAddress), "email", "email_address")
+//
+// RegisterType panics, when the native type name is already registered.
+func RegisterType(t Type, nativeTypes ...string) {
+	if len(nativeTypes) == 0 {
+		nativeTypes = []string{t.NativeType()}
+	}
+
+	registeredTypes.Lock()
+	defer registeredTypes.Unlock()
+
+	for _, nativeType := range nativeTypes {
+		nativeType = strings.ToLower(nativeType)
+		if _, dup := registeredTypes.types[nativeType]; dup {
+			panic(fmt.Sprintf("activerecord: registering duplicate type %q", nativeType))
+		}
+		registeredTypes.types[nativeType] = t
+	}
+}
+
+// LookupType returns the type registered under the native type name.
+func LookupType(nativeType string) (Type, bool) {
+	registeredTypes.RLock()
+	defer registeredTypes.RUnlock()
+
+	t, ok := registeredTypes.types[strings.ToLower(nativeType)]
+	return t, ok
+}
+
 type Nil struct {
 	Type
 }
@@ -49,6 +91,21 @@ func (n Nil) Deserialize(value interface{}) (interface{}, error) {
 	return n.Type.Deserialize(value)
 }
 
+func (n Nil) Serialize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return n.Type.Serialize(value)
+}
+
+// underlyingType returns the type of values, which could be wrapped into Nil.
+func underlyingType(t Type) Type {
+	if n, ok := t.(Nil); ok {
+		return n.Type
+	}
+	return t
+}
+
 type Int64 struct{}
 
 func (*Int64) NativeType() string { return "INTEGER" }
@@ -227,9 +284,11 @@ func (t *Time) Serialize(value interface{}) (interface{}, error) {
 	return value, nil
 }
 
+// JSON is a type of JSON objects, values of the type are represented as
+// activesupport.Hash.
 type JSON struct{}
 
-func (*JSON) NativeType() string { return "VARCHAR" }
+func (*JSON) NativeType() string { return "JSON" }
 
 func (*JSON) String() string { return "json" }
 
@@ -239,6 +298,10 @@ func (j *JSON) Deserialize(value interface{}) (interface{}, error) {
 		err  error
 	)
 	switch value := value.(type) {
+	case activesupport.Hash:
+		return value, nil
+	case map[string]interface{}:
+		return activesupport.Hash(value), nil
 	case string:
 		err = json.Unmarshal([]byte(value), &hash)
 	case []byte:
@@ -249,9 +312,209 @@ func (j *JSON) Deserialize(value interface{}) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return value, nil
+	return hash, nil
 }
 
 func (j *JSON) Serialize(value interface{}) (interface{}, error) {
-	return json.Marshal(value)
+	if value == nil {
+		return nil, nil
+	}
+	hash, err := j.Deserialize(value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(hash)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Decimal is an arbitrary-precision decimal number type, values of the type
+// are represented as activesupport.BigDecimal.
+//
+// SQLite stores decimal numbers as text to keep all significant digits, so the
+// database compares and orders them as strings.
+type Decimal struct{}
+
+func (*Decimal) NativeType() string { return "DECIMAL" }
+
+func (*Decimal) String() string { return "decimal" }
+
+func (d *Decimal) Deserialize(value interface{}) (interface{}, error) {
+	var (
+		decval activesupport.BigDecimal
+		err    error
+	)
+	switch value := value.(type) {
+	case activesupport.BigDecimal:
+		decval = value
+	case string:
+		decval, err = activesupport.ParseBigDecimal(value)
+	case []byte:
+		decval, err = activesupport.ParseBigDecimal(string(value))
+	case int:
+		decval = activesupport.NewBigDecimal(int64(value), 0)
+	case int64:
+		decval = activesupport.NewBigDecimal(value, 0)
+	case float64:
+		decval = activesupport.BigDecimalFromFloat64(value)
+	default:
+		err = ErrType{TypeName: d.String(), Value: value}
+	}
+	if err != nil {
+		return nil, ErrType{TypeName: d.String(), Value: value}
+	}
+	return decval, nil
+}
+
+func (d *Decimal) Serialize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	decval, err := d.Deserialize(value)
+	if err != nil {
+		return nil, err
+	}
+	return decval.(activesupport.BigDecimal).String(), nil
+}
+
+// UUID is a type of universally unique identifiers, values of the type are
+// represented as lower-case strings in canonical form. Primary keys of UUID
+// type are generated on insert, when the value is not set explicitly.
+type UUID struct{}
+
+func (*UUID) NativeType() string { return "UUID" }
+
+func (*UUID) String() string { return "uuid" }
+
+func (u *UUID) Deserialize(value interface{}) (interface{}, error) {
+	var strval string
+	switch value := value.(type) {
+	case string:
+		strval = value
+	case []byte:
+		// Binary representation of the identifier.
+		if len(value) == 16 {
+			return formatUUID(value), nil
+		}
+		strval = string(value)
+	default:
+		return nil, ErrType{TypeName: u.String(), Value: value}
+	}
+
+	b, err := hex.DecodeString(strings.ReplaceAll(strval, "-", ""))
+	if err != nil || len(b) != 16 || (len(strval) != 32 && len(strval) != 36) {
+		return nil, ErrType{TypeName: u.String(), Value: value}
+	}
+	return formatUUID(b), nil
+}
+
+func (u *UUID) Serialize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return u.Deserialize(value)
+}
+
+// NewUUID returns a new random (version 4) UUID.
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4.
+	b[8] = (b[8] & 0x3f) | 0x80 // Variant RFC 4122.
+	return formatUUID(b), nil
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// Binary is a type of arbitrary binary data, values of the type are
+// represented as byte slices.
+type Binary struct{}
+
+func (*Binary) NativeType() string { return "BLOB" }
+
+func (*Binary) String() string { return "binary" }
+
+func (b *Binary) Deserialize(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	default:
+		return nil, ErrType{TypeName: b.String(), Value: value}
+	}
+}
+
+func (b *Binary) Serialize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return b.Deserialize(value)
+}
+
+// Enum is a string type restricted to the list of values. When the list is
+// empty, any string is accepted.
+//
+// When Integer is set, values are stored as indexes of the list, so the order
+// of values must not be changed once records are persisted.
+type Enum struct {
+	Values  []string
+	Integer bool
+}
+
+func (e *Enum) NativeType() string {
+	if e.Integer {
+		return "INTEGER"
+	}
+	return "VARCHAR"
+}
+
+func (*Enum) String() string { return "enum" }
+
+func (e *Enum) Deserialize(value interface{}) (interface{}, error) {
+	var strval string
+	switch value := value.(type) {
+	case string:
+		strval = value
+	case []byte:
+		strval = string(value)
+	case int64:
+		if !e.Integer || value < 0 || value >= int64(len(e.Values)) {
+			return nil, ErrType{TypeName: e.String(), Value: value}
+		}
+		return e.Values[value], nil
+	case int:
+		return e.Deserialize(int64(value))
+	default:
+		return nil, ErrType{TypeName: e.String(), Value: value}
+	}
+
+	if len(e.Values) != 0 && !activesupport.Strings(e.Values...).Contains(strval) {
+		return nil, ErrType{TypeName: e.String(), Value: value}
+	}
+	return strval, nil
+}
+
+func (e *Enum) Serialize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	value, err := e.Deserialize(value)
+	if err != nil || !e.Integer {
+		return value, err
+	}
+	for i, v := range e.Values {
+		if v == value {
+			return int64(i), nil
+		}
+	}
+	return nil, ErrType{TypeName: e.String(), Value: value}
 }
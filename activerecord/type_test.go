@@ -0,0 +1,91 @@
+package activerecord_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/activegraph/activegraph/activerecord"
+	. "github.com/activegraph/activegraph/activesupport"
+)
+
+// Email is a value object of the email address.
+type Email struct {
+	User   string
+	Domain string
+}
+
+func (e Email) String() string {
+	return e.User + "@" + e.Domain
+}
+
+// EmailAddress is a custom attribute type of email addresses.
+type EmailAddress struct{}
+
+func (*EmailAddress) NativeType() string { return "EMAIL" }
+
+func (*EmailAddress) String() string { return "email" }
+
+func (ea *EmailAddress) Deserialize(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case Email:
+		return value, nil
+	case string:
+		parts := strings.Split(value, "@")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, activerecord.ErrType{TypeName: ea.String(), Value: value}
+		}
+		return Email{User: parts[0], Domain: parts[1]}, nil
+	default:
+		return nil, activerecord.ErrType{TypeName: ea.String(), Value: value}
+	}
+}
+
+func (ea *EmailAddress) Serialize(value interface{}) (interface{}, error) {
+	email, err := ea.Deserialize(value)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprint(email), nil
+}
+
+func init() {
+	activerecord.RegisterType(new(EmailAddress))
+}
+
+func TestRegisterType(t *testing.T) {
+	_, err := activerecord.EstablishConnection(activerecord.DatabaseConfig{
+		Adapter:  "sqlite3",
+		Database: t.Name() + ".db",
+	})
+	require.NoError(t, err)
+
+	defer os.Remove(t.Name() + ".db")
+	defer activerecord.RemoveConnection("primary")
+
+	activerecord.Migrate(t.Name(), func(m *activerecord.M) {
+		m.CreateTable("users", func(t *activerecord.Table) {
+			t.DefineColumn("email", new(EmailAddress), activerecord.NotNull())
+		})
+	})
+
+	User := activerecord.New("user")
+	require.IsType(t, new(EmailAddress), User.AttributeForInspect("email").AttributeType())
+
+	user := User.Create(Hash{"email": Email{User: "lem", Domain: "example.com"}}).Unwrap()
+
+	user = User.Find(user.ID()).Unwrap()
+	require.Equal(t, Email{User: "lem", Domain: "example.com"}, user.Attribute("email"))
+
+	var schema strings.Builder
+	require.NoError(t, activerecord.DumpSchema(&schema, activerecord.SchemaGo))
+	require.Contains(t, schema.String(), `"github.com/activegraph/activegraph/activerecord_test"`)
+	require.Contains(t, schema.String(),
+		`t.DefineColumn("email", new(activerecord_test.EmailAddress), activerecord.NotNull())`)
+
+	// Registration of the same native type twice is forbidden.
+	require.Panics(t, func() { activerecord.RegisterType(new(EmailAddress), "email") })
+}
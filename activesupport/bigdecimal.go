@@ -0,0 +1,226 @@
+package activesupport
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var bigTen = big.NewInt(10)
+
+// MaxBigDecimalScale is the maximum absolute scale of parsed decimal numbers,
+// numbers with greater exponents are rejected, since computations with them
+// take unbounded time and memory.
+const MaxBigDecimalScale = 1000
+
+// BigDecimal is an arbitrary-precision decimal number, represented as an
+// unscaled integer value and a scale, so the number is unscaled * 10^-scale.
+//
+// The zero value of BigDecimal is zero.
+type BigDecimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewBigDecimal returns a decimal number unscaled * 10^-scale.
+//
+//	NewBigDecimal(1999, 2) // 19.99
+func NewBigDecimal(unscaled int64, scale int32) BigDecimal {
+	return BigDecimal{unscaled: big.NewInt(unscaled), scale: scale}.normalize()
+}
+
+// ParseBigDecimal parses decimal number from the string, exponent notation is
+// supported, e.g. "19.99", "-0.5", "1.5e3". Numbers with the absolute scale
+// greater than MaxBigDecimalScale are rejected.
+func ParseBigDecimal(s string) (BigDecimal, error) {
+	errSyntax := fmt.Errorf("activesupport: invalid decimal %q", s)
+
+	str := strings.TrimSpace(s)
+	var exp int64
+
+	if pos := strings.IndexAny(str, "eE"); pos != -1 {
+		var err error
+		if exp, err = strconv.ParseInt(str[pos+1:], 10, 32); err != nil {
+			return BigDecimal{}, errSyntax
+		}
+		str = str[:pos]
+	}
+
+	var sign string
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		sign, str = str[:1], str[1:]
+	}
+
+	intPart, fracPart := str, ""
+	if pos := strings.IndexByte(str, '.'); pos != -1 {
+		intPart, fracPart = str[:pos], str[pos+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return BigDecimal{}, errSyntax
+	}
+	for _, ch := range intPart + fracPart {
+		if ch < '0' || ch > '9' {
+			return BigDecimal{}, errSyntax
+		}
+	}
+
+	// The exponent is limited to 32 bits, so the scale never overflows 64 bits.
+	scale := int64(len(fracPart)) - exp
+	if scale > MaxBigDecimalScale || scale < -MaxBigDecimalScale {
+		return BigDecimal{}, fmt.Errorf("activesupport: decimal %q is out of range", s)
+	}
+
+	unscaled, ok := new(big.Int).SetString(sign+intPart+fracPart, 10)
+	if !ok {
+		return BigDecimal{}, errSyntax
+	}
+	return BigDecimal{unscaled: unscaled, scale: int32(scale)}.normalize(), nil
+}
+
+// BigDecimalFromFloat64 returns a decimal number with the shortest representation
+// of the floating-point number.
+func BigDecimalFromFloat64(f float64) BigDecimal {
+	d, err := ParseBigDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// normalize ensures that scale of the number is not negative.
+func (d BigDecimal) normalize() BigDecimal {
+	if d.scale < 0 {
+		return d.rescale(0)
+	}
+	return d
+}
+
+func (d BigDecimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the number with the greater scale, the value is preserved.
+func (d BigDecimal) rescale(scale int32) BigDecimal {
+	if scale <= d.scale {
+		return d
+	}
+	factor := new(big.Int).Exp(bigTen, big.NewInt(int64(scale-d.scale)), nil)
+	return BigDecimal{unscaled: new(big.Int).Mul(d.int(), factor), scale: scale}
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d BigDecimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the number.
+func (d BigDecimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Cmp compares numbers and returns -1 if d < other, 0 if d == other and +1
+// if d > other.
+func (d BigDecimal) Cmp(other BigDecimal) int {
+	a, b := d.rescale(other.scale), other.rescale(d.scale)
+	return a.int().Cmp(b.int())
+}
+
+// Equal returns true, when numbers are equal regardless of their scales.
+func (d BigDecimal) Equal(other BigDecimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Add returns the sum d + other.
+func (d BigDecimal) Add(other BigDecimal) BigDecimal {
+	a, b := d.rescale(other.scale), other.rescale(d.scale)
+	return BigDecimal{unscaled: new(big.Int).Add(a.int(), b.int()), scale: a.scale}
+}
+
+// Sub returns the difference d - other.
+func (d BigDecimal) Sub(other BigDecimal) BigDecimal {
+	a, b := d.rescale(other.scale), other.rescale(d.scale)
+	return BigDecimal{unscaled: new(big.Int).Sub(a.int(), b.int()), scale: a.scale}
+}
+
+// Mul returns the product d * other.
+func (d BigDecimal) Mul(other BigDecimal) BigDecimal {
+	return BigDecimal{
+		unscaled: new(big.Int).Mul(d.int(), other.int()),
+		scale:    d.scale + other.scale,
+	}
+}
+
+// Round returns the number rounded half away from zero to the scale.
+func (d BigDecimal) Round(scale int32) BigDecimal {
+	if scale >= d.scale {
+		return d.rescale(scale)
+	}
+
+	var (
+		divisor  = new(big.Int).Exp(bigTen, big.NewInt(int64(d.scale-scale)), nil)
+		abs      = new(big.Int).Abs(d.int())
+		quo, rem = new(big.Int).QuoRem(abs, divisor, new(big.Int))
+	)
+
+	if rem.Lsh(rem, 1).Cmp(divisor) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if d.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return BigDecimal{unscaled: quo, scale: scale}.normalize()
+}
+
+// Float64 returns the nearest floating-point number.
+func (d BigDecimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String returns the number in the decimal notation, e.g. "19.99".
+func (d BigDecimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+
+	var sign string
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+
+	if pad := int(d.scale) - len(digits) + 1; pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Value implements driver.Valuer interface, the number is passed to the
+// database as a string to preserve the precision.
+func (d BigDecimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes the number as a JSON string to preserve the precision.
+func (d BigDecimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes the number from a JSON string or a JSON number.
+func (d *BigDecimal) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+
+	decimal, err := ParseBigDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = decimal
+	return nil
+}
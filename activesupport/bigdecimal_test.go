@@ -0,0 +1,64 @@
+package activesupport
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBigDecimal(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"19.99", "19.99"},
+		{"-0.5", "-0.5"},
+		{".25", "0.25"},
+		{"1.5e3", "1500"},
+		{"15e-4", "0.0015"},
+		{"12345678901234567890.0123456789", "12345678901234567890.0123456789"},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseBigDecimal(tt.s)
+		if err != nil {
+			t.Fatalf("ParseBigDecimal(%q) failed: %v", tt.s, err)
+		}
+		if d.String() != tt.want {
+			t.Fatalf("ParseBigDecimal(%q) = %s, want %s", tt.s, d, tt.want)
+		}
+	}
+
+	// Exponents out of range are rejected instead of being computed for
+	// a long time or overflowing the scale.
+	tooLarge := []string{"1e50000000", "1e-2147483648", "1e1001", "0." + strings.Repeat("1", 1001)}
+
+	for _, s := range append([]string{"", ".", "1.2.3", "abc", "1e"}, tooLarge...) {
+		if _, err := ParseBigDecimal(s); err == nil {
+			t.Fatalf("ParseBigDecimal(%q) expected to fail", s)
+		}
+	}
+}
+
+func TestBigDecimal_Arithmetic(t *testing.T) {
+	var (
+		price    = NewBigDecimal(1999, 2)
+		discount = NewBigDecimal(15, 2)
+		quantity = NewBigDecimal(3, 0)
+	)
+
+	if s := price.Mul(quantity).String(); s != "59.97" {
+		t.Fatalf("19.99 * 3 = %s, want 59.97", s)
+	}
+	if s := price.Sub(price.Mul(discount)).Round(2).String(); s != "16.99" {
+		t.Fatalf("19.99 - 19.99 * 0.15 = %s, want 16.99", s)
+	}
+	if s := NewBigDecimal(-125, 2).Round(1).String(); s != "-1.3" {
+		t.Fatalf("round(-1.25) = %s, want -1.3", s)
+	}
+	if !NewBigDecimal(10, 1).Equal(NewBigDecimal(1, 0)) {
+		t.Fatalf("1.0 != 1")
+	}
+	if price.Add(discount).Cmp(price) != 1 {
+		t.Fatalf("19.99 + 0.15 <= 19.99")
+	}
+}
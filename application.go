@@ -1,18 +1,48 @@
 package activegraph
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/activegraph/activegraph/actioncontroller"
 	"github.com/activegraph/activegraph/actioncontroller/graphql"
+	"github.com/activegraph/activegraph/activerecord"
 )
 
 type A struct {
 	actioncontroller.Mapper
+
+	registry *activerecord.Registry
+	err      error
+}
+
+// Registry sets the registry of database connections and models used by the
+// application. By default application uses activerecord.DefaultRegistry.
+//
+//	registry := activerecord.NewRegistry()
+//	Author := registry.New("author")
+//
+//	app := activegraph.New(func(a *activegraph.A) {
+//		a.Registry(registry)
+//		a.Resources(Author, AuthorController)
+//	})
+func (a *A) Registry(registry *activerecord.Registry) {
+	a.registry = registry
+}
+
+// Resources maps the model and controller to the application routes. The model
+// must be defined within the registry of the application.
+func (a *A) Resources(model actioncontroller.AbstractModel, controller actioncontroller.AbstractController) {
+	if _, err := a.registry.Reflection().Reflection(model.Name()); err != nil && a.err == nil {
+		a.err = fmt.Errorf("model %q is not defined within the application registry", model.Name())
+	}
+	a.Mapper.Resources(model, controller)
 }
 
 type Application struct {
-	mapper actioncontroller.Mapper
+	mapper   actioncontroller.Mapper
+	registry *activerecord.Registry
 }
 
 func New(init func(*A)) *Application {
@@ -24,13 +54,28 @@ func New(init func(*A)) *Application {
 }
 
 func Initialize(init func(*A)) (*Application, error) {
-	a := A{Mapper: new(graphql.Mapper)}
+	a := A{Mapper: new(graphql.Mapper), registry: activerecord.DefaultRegistry()}
 	init(&a)
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return &Application{mapper: a.Mapper, registry: a.registry}, nil
+}
 
-	return &Application{mapper: a.Mapper}, nil
+// Registry returns the registry of database connections and models used by
+// the application.
+func (a *Application) Registry() *activerecord.Registry {
+	return a.registry
 }
 
 func (a *Application) ListenAndServe() error {
+	// Ensure connections of the application registry are alive before
+	// accepting requests.
+	if err := a.registry.Ping(context.Background()); err != nil {
+		return err
+	}
+
 	handler, err := a.mapper.Map()
 	if err != nil {
 		return err
@@ -4,7 +4,7 @@ go 1.18
 
 require (
 	github.com/davecgh/go-spew v1.1.1
-	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/stretchr/testify v1.4.0
 	github.com/vektah/gqlparser/v2 v2.2.0
 )